@@ -27,7 +27,7 @@ func main() {
 	repo := promptsRepo.NewPromptRepository(db)
 
 	// Initialize Usecase
-	uc := usecases.NewPromptUsecase(repo)
+	uc := usecases.NewPromptUsecase(repo, nil, nil, nil, nil)
 	_ = uc
 
 	// Setup Routes (stub)
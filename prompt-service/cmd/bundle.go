@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/blcvn/backend/services/prompt-service/helper"
+	"github.com/blcvn/backend/services/prompt-service/repository/postgres"
+	"github.com/blcvn/backend/services/prompt-service/usecases"
+	"github.com/spf13/cobra"
+)
+
+type iBundleUsecase interface {
+	ImportTemplates(ctx context.Context, files []*entities.PromptFile, dryRun bool) (*entities.ImportReport, errors.BaseError)
+	ExportTemplates(ctx context.Context, format string) ([]*entities.PromptFile, errors.BaseError)
+}
+
+var (
+	importDryRun bool
+	exportFormat string
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <dir>",
+	Short: "Import a directory of prompt files",
+	Long: `Import creates or updates a template for every YAML (.yaml, .yml) and
+Markdown (.md) prompt file under dir. Markdown files keep name, description,
+variables and tags in YAML frontmatter and the content in the body.
+
+Importing is idempotent: changed templates get a new version and unchanged
+ones are left alone. With --dry-run nothing is written and the changes are
+printed as a diff.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runImport,
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export <dir>",
+	Short: "Export every template as a prompt file",
+	Long: `Export writes every template to dir in the format read by import, one
+file per template and locale. Chat templates are always written as YAML.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runExport,
+}
+
+func init() {
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "print the changes without applying them")
+	exportCmd.Flags().StringVar(&exportFormat, "format", helper.BundleFormatMarkdown, "file format for text templates: md or yaml")
+}
+
+func runImport(cmd *cobra.Command, args []string) {
+	files, err := helper.ReadPromptBundle(args[0])
+	if err != nil {
+		log.Fatalf("Failed to read prompts: %v", err)
+	}
+
+	report, importErr := newBundleUsecase().ImportTemplates(commandContext(context.Background(), "import"), files, importDryRun)
+	if importErr != nil {
+		log.Fatalf("Import failed: %v", importErr)
+	}
+
+	counts := make(map[entities.ImportAction]int)
+	for _, change := range report.Changes {
+		counts[change.Action]++
+		if change.Action == entities.ImportUnchanged {
+			continue
+		}
+		fmt.Printf("%-9s %s (%s) %s\n", change.Action, change.Name, change.Locale, change.Version)
+		if importDryRun {
+			fmt.Print(change.Diff)
+		}
+	}
+	summary := fmt.Sprintf("%d created, %d updated, %d unchanged",
+		counts[entities.ImportCreate], counts[entities.ImportUpdate], counts[entities.ImportUnchanged])
+	if importDryRun {
+		summary = "Dry run: " + summary
+	}
+	fmt.Println(summary)
+}
+
+func runExport(cmd *cobra.Command, args []string) {
+	files, err := newBundleUsecase().ExportTemplates(context.Background(), exportFormat)
+	if err != nil {
+		log.Fatalf("Export failed: %v", err)
+	}
+	if err := helper.WritePromptBundle(args[0], files, exportFormat); err != nil {
+		log.Fatalf("Failed to write prompts: %v", err)
+	}
+	fmt.Printf("Exported %d templates to %s\n", len(files), args[0])
+}
+
+func newBundleUsecase() iBundleUsecase {
+	db, err := openDatabase(getEnv("DATABASE_URL", defaultDatabaseURL))
+	if err != nil {
+		log.Fatalf("Failed to connect to db: %v", err)
+	}
+	locales, err := localeSettings()
+	if err != nil {
+		log.Fatalf("Invalid locale settings: %v", err)
+	}
+	audit := usecases.NewAuditUsecase(postgres.NewAuditRepository(db))
+	return usecases.NewPromptUsecase(postgres.NewPromptRepository(db), nil, locales, nil, audit)
+}
+
+// commandContext attributes the changes a command makes to the local user
+func commandContext(ctx context.Context, operation string) context.Context {
+	return entities.ContextWithRequest(ctx, entities.RequestInfo{
+		Actor:     entities.Actor{ID: getEnv("USER", "cli")},
+		Operation: operation,
+	})
+}
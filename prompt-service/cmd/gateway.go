@@ -0,0 +1,421 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/textproto"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-kratos/kratos/v2"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware/logging"
+	"github.com/go-kratos/kratos/v2/middleware/recovery"
+	"github.com/go-kratos/kratos/v2/middleware/tracing"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/grpc"
+	"github.com/go-kratos/kratos/v2/transport/http"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
+
+	"github.com/blcvn/backend/services/prompt-service/common/configs"
+	"github.com/blcvn/backend/services/prompt-service/common/mtls"
+	"github.com/blcvn/backend/services/prompt-service/controllers"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/blcvn/backend/services/prompt-service/helper"
+	"github.com/blcvn/backend/services/prompt-service/repository/postgres"
+	"github.com/blcvn/backend/services/prompt-service/usecases"
+	pb "github.com/blcvn/kratos-proto/go/prompt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	pgDriver "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var (
+	serviceName string
+	jaegerUrl   string
+	metricsPath string
+	grpcPort    int
+	httpPort    int
+)
+
+var gatewayCmd = &cobra.Command{
+	Use:     "gateway",
+	Aliases: []string{"serve"},
+	Short:   "Start the prompt service gateway",
+	Long:    "Start the prompt service with gRPC and HTTP gateway servers",
+	Run: func(cmd *cobra.Command, args []string) {
+		Gateway(serviceName, jaegerUrl, metricsPath, grpcPort, httpPort)
+	},
+}
+
+// The flags default to the environment, as the container sets it
+func init() {
+	gatewayCmd.Flags().StringVar(&serviceName, "service-name", getEnv("SERVICE_NAME", "prompt-service"), "Name of the service")
+	gatewayCmd.Flags().StringVar(&jaegerUrl, "jaeger-url", getEnv("JAEGER_URL", "localhost:4317"), "Jaeger OTLP endpoint URL")
+	gatewayCmd.Flags().StringVar(&metricsPath, "metrics-path", getEnv("METRICS_PATH", "/metrics"), "Path for Prometheus metrics endpoint")
+	gatewayCmd.Flags().IntVar(&grpcPort, "grpc-port", getEnvInt("GRPC_PORT", 9086), "gRPC server port")
+	gatewayCmd.Flags().IntVar(&httpPort, "http-port", getEnvInt("HTTP_PORT", 8086), "HTTP server port")
+}
+
+// iPromptController is the controller served by both the gRPC server and the
+// gateway, which also mounts its JSON routes
+type iPromptController interface {
+	pb.PromptServiceServer
+	RegisterHTTPRoutes(mux *runtime.ServeMux) error
+}
+
+// controllerDeps holds all controller dependencies
+type controllerDeps struct {
+	promptCtrl iPromptController
+	// watchPrompts syncs PROMPT_SYNC_DIR until ctx is done; nil without one
+	watchPrompts func(ctx context.Context)
+}
+
+// setTracerProvider configures an OTLP exporter, and configures the corresponding trace provider.
+func setTracerProvider(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(endpoint), otlptracegrpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String("prompt-service"),
+		)),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	fmt.Printf("Tracer & Propagator initialized with endpoint: %s\n", endpoint)
+	return tp.Shutdown, nil
+}
+
+// setupDatabase initializes database connection
+func setupDatabase(appLog *log.Helper, cfg *configs.Config) *gorm.DB {
+	// Use URL from Vault secret config
+	dsn := cfg.Database.URL
+	if dsn == "" {
+		appLog.Fatal("database URL is empty")
+	}
+
+	db, err := openDatabase(dsn)
+	if err != nil {
+		appLog.Fatalf("failed to connect to database: %v", err)
+	}
+
+	// Migrations are applied with cmd/migrate rather than AutoMigrate
+	appLog.Info("database connection established")
+	return db
+}
+
+// setupUsecasesAndControllers wires up usecases and controllers
+func setupUsecasesAndControllers(appLog *log.Helper, cfg *configs.Config) *controllerDeps {
+	// 1. setup database
+	db := setupDatabase(appLog, cfg)
+
+	// 2. setupRepositories initializes all repositories
+	repo := postgres.NewPromptRepository(db)
+	experimentRepo := postgres.NewExperimentRepository(db)
+	auditRepo := postgres.NewAuditRepository(db)
+
+	// 3. Initialize helpers
+	tokenizer, err := helper.NewTokenizer(getEnv("TOKENIZER_VOCAB", ""))
+	if err != nil {
+		appLog.Warnf("BPE tokenizer unavailable, estimating tokens heuristically: %v", err)
+	}
+	locales, err := localeSettings()
+	if err != nil {
+		appLog.Fatalf("invalid locale settings: %v", err)
+	}
+
+	// 4. Initialize usecases
+	reviewerRoles := entities.ParseRoles(getEnv("REVIEWER_ROLES", entities.RoleReviewer))
+	auditUsecase := usecases.NewAuditUsecase(auditRepo)
+	usecase := usecases.NewPromptUsecase(repo, tokenizer, locales, reviewerRoles, auditUsecase)
+	experimentUsecase := usecases.NewExperimentUsecase(experimentRepo, repo, auditUsecase)
+
+	deps := &controllerDeps{}
+	if dir := getEnv("PROMPT_SYNC_DIR", ""); dir != "" {
+		source, err := helper.NewGitSource(dir)
+		if err != nil {
+			appLog.Fatalf("failed to open PROMPT_SYNC_DIR: %v", err)
+		}
+		interval, err := time.ParseDuration(getEnv("PROMPT_SYNC_INTERVAL", "30s"))
+		if err != nil {
+			appLog.Fatalf("invalid PROMPT_SYNC_INTERVAL: %v", err)
+		}
+		deps.watchPrompts = func(ctx context.Context) {
+			watchPrompts(commandContext(ctx, "sync"), usecase, source, interval, false)
+		}
+	}
+
+	// 5. Initialize controllers
+	deps.promptCtrl = controllers.NewPromptController(usecase, experimentUsecase, auditUsecase)
+
+	return deps
+}
+
+// promptSyncHooks run the prompt sync for the lifetime of the app: it starts
+// with the servers, on the app context, and is cancelled once they stop,
+// however the app ends. The app waits for the sync to return before exiting.
+func promptSyncHooks(watch func(ctx context.Context)) []kratos.Option {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+	start := kratos.AfterStart(func(ctx context.Context) error {
+		ctx, cancel = context.WithCancel(ctx)
+		go func() {
+			defer close(done)
+			watch(ctx)
+		}()
+		return nil
+	})
+	wait := kratos.AfterStop(func(context.Context) error {
+		cancel()
+		<-done
+		return nil
+	})
+	return []kratos.Option{start, wait}
+}
+
+// setupGRPCServer creates and configures gRPC server
+func setupGRPCServer(
+	logger log.Logger,
+	grpcPort int,
+	reloader *mtls.CertReloader,
+	ctrls *controllerDeps,
+) transport.Server {
+	grpcOpts := []grpc.ServerOption{
+		grpc.Middleware(
+			recovery.Recovery(),
+			tracing.Server(),
+			logging.Server(logger),
+		),
+		grpc.UnaryInterceptor(controllers.RequestInterceptor),
+		grpc.Address(fmt.Sprintf(":%d", grpcPort)),
+	}
+
+	if reloader != nil {
+		grpcOpts = append(grpcOpts, grpc.TLSConfig(&tls.Config{
+			GetConfigForClient: reloader.GetConfigForClient,
+		}))
+	}
+
+	grpcSrv := grpc.NewServer(grpcOpts...)
+
+	// Register gRPC services
+	pb.RegisterPromptServiceServer(grpcSrv, ctrls.promptCtrl)
+
+	return grpcSrv
+}
+
+// setupHTTPServer creates and configures HTTP server with grpc-gateway
+func setupHTTPServer(
+	ctx context.Context,
+	logger log.Logger,
+	appLog *log.Helper,
+	httpPort int,
+	metricsPath string,
+	reloader *mtls.CertReloader,
+	headers controllers.IdentityHeaders,
+	ctrls *controllerDeps,
+) transport.Server {
+	httpOpts := []http.ServerOption{
+		http.Middleware(
+			recovery.Recovery(),
+			tracing.Server(),
+			logging.Server(logger),
+		),
+		http.Address(fmt.Sprintf(":%d", httpPort)),
+	}
+
+	if reloader != nil {
+		httpOpts = append(httpOpts, http.TLSConfig(&tls.Config{
+			GetConfigForClient: reloader.GetConfigForClient,
+		}))
+	}
+
+	httpSrv := http.NewServer(httpOpts...)
+
+	// Create grpc-gateway mux. Handlers call the controller in-process, so
+	// the middleware takes the place of RequestInterceptor.
+	gwmux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(headerMatcher(headers)),
+		runtime.WithMiddlewares(controllers.RequestMiddleware(headers)),
+	)
+
+	// Register grpc-gateway handlers
+	if err := pb.RegisterPromptServiceHandlerServer(ctx, gwmux, ctrls.promptCtrl); err != nil {
+		appLog.Fatalf("failed to register prompt gateway: %v", err)
+	}
+	if err := ctrls.promptCtrl.RegisterHTTPRoutes(gwmux); err != nil {
+		appLog.Fatalf("failed to register HTTP routes: %v", err)
+	}
+
+	// Add Prometheus metrics endpoint
+	httpSrv.Route("/").GET(metricsPath, func(ctx http.Context) error {
+		promhttp.Handler().ServeHTTP(ctx.Response(), ctx.Request())
+		return nil
+	})
+
+	// Mount grpc-gateway on HTTP server
+	httpSrv.HandlePrefix("/", gwmux)
+
+	return httpSrv
+}
+
+// identityHeaders are the Kong headers set in kong_headers, each falling back
+// to its default
+func identityHeaders(cfg *configs.Config) controllers.IdentityHeaders {
+	headers := controllers.DefaultIdentityHeaders
+	if cfg.KongHeaders.UserIDHeader != "" {
+		headers.UserID = cfg.KongHeaders.UserIDHeader
+	}
+	if cfg.KongHeaders.TenantIDHeader != "" {
+		headers.TenantID = cfg.KongHeaders.TenantIDHeader
+	}
+	if cfg.KongHeaders.RolesHeader != "" {
+		headers.Roles = cfg.KongHeaders.RolesHeader
+	}
+	return headers
+}
+
+// headerMatcher forwards the Kong identity headers to gRPC as the x-user-id,
+// x-tenant-id and x-roles metadata the controllers read, and X-Locale as is,
+// in addition to the standard headers the gateway forwards
+func headerMatcher(headers controllers.IdentityHeaders) runtime.HeaderMatcherFunc {
+	forwarded := map[string]string{
+		textproto.CanonicalMIMEHeaderKey(headers.UserID):   "x-user-id",
+		textproto.CanonicalMIMEHeaderKey(headers.TenantID): "x-tenant-id",
+		textproto.CanonicalMIMEHeaderKey(headers.Roles):    "x-roles",
+		"X-Locale": "x-locale",
+	}
+	return func(key string) (string, bool) {
+		if name, ok := forwarded[textproto.CanonicalMIMEHeaderKey(key)]; ok {
+			return name, true
+		}
+		return runtime.DefaultHeaderMatcher(key)
+	}
+}
+
+// Gateway initializes and runs the prompt service gateway
+func Gateway(serviceName, jaegerUrl, metricsPath string, grpcPort, httpPort int) {
+	ctx := context.Background()
+	// 1. Setup logger
+	logger := log.With(log.NewStdLogger(os.Stdout),
+		"ts", log.DefaultTimestamp,
+		"caller", log.DefaultCaller,
+		"service.name", serviceName,
+	)
+	appLog := log.NewHelper(logger)
+
+	// 2. Initialize configs
+	cfg, err := configs.LoadConfig()
+	if err != nil {
+		appLog.Fatalf("failed to load configs: %v", err)
+	}
+
+	// 3. Initialize tracing
+	traceShutdown, err := setTracerProvider(ctx, jaegerUrl)
+	if err != nil {
+		fmt.Printf("failed to init tracer: %v\n", err)
+	} else {
+		defer traceShutdown(ctx)
+		// Test span
+		tr := otel.Tracer(serviceName)
+		_, span := tr.Start(ctx, fmt.Sprintf("%s-startup", serviceName))
+		span.End()
+		fmt.Println("Sent test span to Jaeger")
+	}
+
+	// 4. Setup infrastructure
+	ctrls := setupUsecasesAndControllers(appLog, cfg)
+
+	// 5. Setup mTLS
+	tlsCertPath := "/vault/secrets/tls.crt"
+	tlsKeyPath := "/vault/secrets/tls.key"
+	if cfg.Mtls.CertPath != "" && cfg.Mtls.KeyPath != "" {
+		tlsCertPath, tlsKeyPath = cfg.Mtls.CertPath, cfg.Mtls.KeyPath
+	}
+
+	reloader, err := mtls.NewCertReloader(tlsCertPath, tlsKeyPath, cfg.Mtls.CAPath)
+	if err != nil {
+		appLog.Warnf("failed to load mTLS certificates: %v", err)
+	}
+
+	// 6. Setup servers
+	services := []transport.Server{}
+
+	if grpcPort > 0 {
+		grpcSrv := setupGRPCServer(logger, grpcPort, reloader, ctrls)
+		services = append(services, grpcSrv)
+	}
+
+	if httpPort > 0 {
+		httpSrv := setupHTTPServer(ctx, logger, appLog, httpPort, metricsPath, reloader, identityHeaders(cfg), ctrls)
+		services = append(services, httpSrv)
+	}
+
+	if len(services) == 0 {
+		appLog.Fatal("no server configured")
+	}
+
+	// Create and run Kratos application
+	options := []kratos.Option{
+		kratos.Name(serviceName),
+		kratos.Logger(logger),
+		kratos.Server(services...),
+	}
+	if ctrls.watchPrompts != nil {
+		options = append(options, promptSyncHooks(ctrls.watchPrompts)...)
+	}
+	app := kratos.New(options...)
+
+	appLog.Infof("Starting %s with gRPC on :%d and HTTP on :%d", serviceName, grpcPort, httpPort)
+	if err := app.Run(); err != nil {
+		appLog.Fatal(err)
+	}
+}
+
+// openDatabase connects to Postgres
+func openDatabase(dbURL string) (*gorm.DB, error) {
+	return gorm.Open(pgDriver.New(pgDriver.Config{
+		DSN:                  dbURL,
+		PreferSimpleProtocol: true,
+	}), &gorm.Config{})
+}
+
+// localeSettings reads the supported template locales and their fallbacks
+func localeSettings() (*helper.Locales, error) {
+	return helper.NewLocales(
+		getEnv("DEFAULT_LOCALE", helper.DefaultLocale),
+		getEnv("SUPPORTED_LOCALES", "vi,en"),
+		getEnv("LOCALE_FALLBACKS", ""),
+	)
+}
+
+func getEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// getEnvInt reads an integer from the environment, falling back to def when
+// it is unset or not a number
+func getEnvInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/blcvn/backend/services/prompt-service/helper"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncInterval time.Duration
+	syncOnce     bool
+	syncDryRun   bool
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync <dir>",
+	Short: "Keep templates in sync with a directory of prompt files",
+	Long: `Sync watches dir, typically a prompts repository checked out from git, and
+imports its prompt files whenever they or the checked-out commit change, as
+the import command does. Each version created this way records the file it
+came from and the commit, marked "-dirty" if the file had uncommitted changes.
+
+Removing a file does not delete or archive its template.
+
+The same sync runs inside gateway when PROMPT_SYNC_DIR is set.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runSync,
+}
+
+func init() {
+	syncCmd.Flags().DurationVar(&syncInterval, "interval", 30*time.Second, "how often to check for changes")
+	syncCmd.Flags().BoolVar(&syncOnce, "once", false, "sync once and exit")
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "log the changes without applying them")
+}
+
+func runSync(cmd *cobra.Command, args []string) {
+	source, err := helper.NewGitSource(args[0])
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", args[0], err)
+	}
+	usecase := newBundleUsecase()
+
+	if syncOnce {
+		if _, err := syncPrompts(commandContext(context.Background(), "sync"), usecase, source, "", syncDryRun); err != nil {
+			log.Fatalf("Sync failed: %v", err)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(commandContext(context.Background(), "sync"), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	watchPrompts(ctx, usecase, source, syncInterval, syncDryRun)
+}
+
+// watchPrompts syncs the source every interval until ctx is done. Failed
+// syncs are logged and retried on the next tick.
+func watchPrompts(ctx context.Context, usecase iBundleUsecase, source *helper.GitSource, interval time.Duration, dryRun bool) {
+	log.Printf("Watching prompt files every %s", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		fingerprint, err := syncPrompts(ctx, usecase, source, last, dryRun)
+		if err != nil {
+			log.Printf("Prompt sync failed: %v", err)
+		} else {
+			last = fingerprint
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncPrompts imports the source unless its fingerprint is still last, and
+// returns the fingerprint it saw
+func syncPrompts(ctx context.Context, usecase iBundleUsecase, source *helper.GitSource, last string, dryRun bool) (string, error) {
+	snapshot, err := source.Snapshot()
+	if err != nil {
+		return "", err
+	}
+	if snapshot.Fingerprint == last {
+		return last, nil
+	}
+
+	report, importErr := usecase.ImportTemplates(ctx, snapshot.Files, dryRun)
+	if importErr != nil {
+		return "", importErr
+	}
+	changed := 0
+	for _, change := range report.Changes {
+		if change.Action == entities.ImportUnchanged {
+			continue
+		}
+		changed++
+		log.Printf("Prompt sync: %s %s (%s) %s from %s", change.Action, change.Name, change.Locale, change.Version, change.Path)
+		if dryRun {
+			log.Print(change.Diff)
+		}
+	}
+	log.Printf("Prompt sync at %s: %d of %d templates changed", shortCommit(snapshot.Commit), changed, len(report.Changes))
+	return snapshot.Fingerprint, nil
+}
+
+func shortCommit(commit string) string {
+	if commit == "" {
+		return "working tree"
+	}
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
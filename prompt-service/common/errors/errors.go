@@ -6,6 +6,7 @@ type ErrorCode int
 
 const (
 	BAD_REQUEST    ErrorCode = 400
+	FORBIDDEN      ErrorCode = 403
 	NOT_FOUND      ErrorCode = 404
 	CONFLICT_ERROR ErrorCode = 409
 	INTERNAL_ERROR ErrorCode = 500
@@ -28,7 +29,8 @@ func NewBaseError(code ErrorCode, err error) BaseError {
 func (e *baseError) Error() string      { return e.err.Error() }
 func (e *baseError) GetCode() ErrorCode { return e.code }
 
-func BadRequest(msg string) BaseError { return NewBaseError(BAD_REQUEST, fmt.Errorf(msg)) }
-func NotFound(msg string) BaseError   { return NewBaseError(NOT_FOUND, fmt.Errorf(msg)) }
-func Conflict(msg string) BaseError   { return NewBaseError(CONFLICT_ERROR, fmt.Errorf(msg)) }
+func BadRequest(msg string) BaseError { return NewBaseError(BAD_REQUEST, fmt.Errorf("%s", msg)) }
+func Forbidden(msg string) BaseError  { return NewBaseError(FORBIDDEN, fmt.Errorf("%s", msg)) }
+func NotFound(msg string) BaseError   { return NewBaseError(NOT_FOUND, fmt.Errorf("%s", msg)) }
+func Conflict(msg string) BaseError   { return NewBaseError(CONFLICT_ERROR, fmt.Errorf("%s", msg)) }
 func Internal(err error) BaseError    { return NewBaseError(INTERNAL_ERROR, err) }
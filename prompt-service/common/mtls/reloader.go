@@ -0,0 +1,106 @@
+// Package mtls serves TLS certificates that Vault Agent rotates on disk. It
+// mirrors the CertReloader of ba-shared-libs/pkg/mtls, which prompt-service
+// cannot import until the shared libraries are part of its build.
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertReloader hands out the certificate at certPath and keyPath, loading
+// it again whenever either file changes. With a CA, clients must present a
+// certificate it signed.
+type CertReloader struct {
+	certPath string
+	keyPath  string
+	caPath   string
+
+	mu       sync.RWMutex
+	config   *tls.Config
+	modified time.Time
+}
+
+// NewCertReloader loads the certificate, and the client CA when caPath is
+// set, failing if they cannot be read
+func NewCertReloader(certPath, keyPath, caPath string) (*CertReloader, error) {
+	r := &CertReloader{certPath: certPath, keyPath: keyPath, caPath: caPath}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetConfigForClient returns the TLS config for a handshake, for use as
+// tls.Config.GetConfigForClient. A certificate that fails to reload is
+// reported and the previous one kept.
+func (r *CertReloader) GetConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	if modified, err := r.lastModified(); err == nil && modified.After(r.loadedAt()) {
+		if err := r.reload(); err != nil {
+			fmt.Printf("failed to reload mTLS certificates: %v\n", err)
+		}
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config, nil
+}
+
+func (r *CertReloader) reload() error {
+	modified, err := r.lastModified()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("load key pair: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if r.caPath != "" {
+		pem, err := os.ReadFile(r.caPath)
+		if err != nil {
+			return fmt.Errorf("read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates in client CA %s", r.caPath)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	r.mu.Lock()
+	r.config, r.modified = config, modified
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *CertReloader) loadedAt() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.modified
+}
+
+// lastModified returns when the certificate files last changed
+func (r *CertReloader) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certPath, r.keyPath, r.caPath} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
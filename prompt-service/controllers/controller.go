@@ -2,47 +2,93 @@ package controllers
 
 import (
 	"context"
+	"strings"
 
 	"github.com/blcvn/backend/services/prompt-service/common/errors"
 	"github.com/blcvn/backend/services/prompt-service/entities"
 	"github.com/blcvn/backend/services/prompt-service/helper"
 	pb "github.com/blcvn/kratos-proto/go/prompt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type iPromptUsecase interface {
 	CreateTemplate(ctx context.Context, payload *entities.CreateTemplatePayload) (*entities.PromptTemplate, errors.BaseError)
 	GetTemplate(ctx context.Context, id string) (*entities.PromptTemplate, errors.BaseError)
 	ListTemplates(ctx context.Context, filter *entities.TemplateFilter) ([]*entities.PromptTemplate, int64, errors.BaseError)
+	SearchTemplates(ctx context.Context, filter *entities.TemplateFilter) ([]*entities.TemplateSearchHit, int64, errors.BaseError)
 	UpdateTemplate(ctx context.Context, payload *entities.UpdateTemplatePayload) (*entities.PromptTemplate, errors.BaseError)
 	DeleteTemplate(ctx context.Context, id string) errors.BaseError
+	LintTemplate(ctx context.Context, payload *entities.LintTemplatePayload) (*entities.LintReport, errors.BaseError)
 	RenderTemplate(ctx context.Context, name string, variables map[string]string) (*entities.RenderedPrompt, errors.BaseError)
+	RenderTemplateWithOptions(ctx context.Context, name string, variables map[string]string, opts *entities.RenderOptions) (*entities.RenderedPrompt, errors.BaseError)
+	RenderProviderRequest(ctx context.Context, name string, variables map[string]string, provider entities.Provider, opts *entities.RenderOptions) (*entities.RenderedPrompt, interface{}, errors.BaseError)
+	ListTemplateVersions(ctx context.Context, templateID string) ([]*entities.PromptTemplateVersion, errors.BaseError)
+	GetTemplateVersion(ctx context.Context, templateID string, version string) (*entities.PromptTemplateVersion, errors.BaseError)
+	RollbackTemplate(ctx context.Context, templateID string, version string) (*entities.PromptTemplate, errors.BaseError)
+	DiffTemplateVersions(ctx context.Context, templateID string, from string, to string) (*entities.TemplateDiff, errors.BaseError)
+	ListTemplateDependents(ctx context.Context, templateID string) ([]*entities.TemplateDependent, errors.BaseError)
+	ListTags(ctx context.Context) ([]*entities.TagCount, errors.BaseError)
+	ListTemplateLabels(ctx context.Context, templateID string) ([]*entities.TemplateLabel, errors.BaseError)
+	SetTemplateLabel(ctx context.Context, templateID string, label string, version string, from string) (*entities.TemplateLabel, errors.BaseError)
+	DeleteTemplateLabel(ctx context.Context, templateID string, label string) errors.BaseError
+	ReviewTemplate(ctx context.Context, payload *entities.ReviewTemplatePayload) (*entities.PromptTemplate, errors.BaseError)
+	ListTemplateReviews(ctx context.Context, templateID string) ([]*entities.TemplateReview, errors.BaseError)
+}
+
+type iAuditUsecase interface {
+	ListAuditEvents(ctx context.Context, filter *entities.AuditFilter) ([]*entities.AuditEvent, int64, errors.BaseError)
 }
 
 type promptController struct {
 	pb.UnimplementedPromptServiceServer
-	usecase   iPromptUsecase
-	transform *helper.Transform
+	usecase     iPromptUsecase
+	experiments iExperimentUsecase
+	audit       iAuditUsecase
+	transform   *helper.Transform
 }
 
-func NewPromptController(usecase iPromptUsecase) *promptController {
+func NewPromptController(usecase iPromptUsecase, experiments iExperimentUsecase, audit iAuditUsecase) *promptController {
 	return &promptController{
-		usecase:   usecase,
-		transform: helper.NewTransform(),
+		usecase:     usecase,
+		experiments: experiments,
+		audit:       audit,
+		transform:   helper.NewTransform(),
 	}
 }
 
-func (c *promptController) CreateTemplate(ctx context.Context, req *pb.CreateTemplateRequest) (*pb.CreateTemplateResponse, error) {
-	// Extract description and tags from metadata if possible, or leave empty
-	description := req.Payload.Metadata["description"]
-	// Tags handling could be added here if passed in metadata
+// RequestInterceptor attaches the caller and the RPC to the context of every
+// gRPC call, so changes can be audited
+func RequestInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx = entities.ContextWithRequest(ctx, entities.RequestInfo{
+		Actor:     incomingActor(ctx),
+		Operation: info.FullMethod,
+	})
+	return handler(ctx, req)
+}
 
+func (c *promptController) CreateTemplate(ctx context.Context, req *pb.CreateTemplateRequest) (*pb.CreateTemplateResponse, error) {
+	// Fields missing from the proto are passed in metadata
 	payload := &entities.CreateTemplatePayload{
 		Name:        req.Payload.Name,
-		Description: description,
+		Locale:      req.Payload.Metadata["locale"],
+		Description: req.Payload.Metadata["description"],
 		Content:     req.Payload.Template,
+		Syntax:      entities.TemplateSyntax(req.Payload.Metadata["syntax"]),
+		Kind:        entities.TemplateKind(req.Payload.Metadata["kind"]),
 		Variables:   c.transform.Pb2Variable(req.Payload.Variables),
-		// Tags:        req.Payload.Tags,
+		Tags:        c.transform.MetadataTags(req.Payload.Metadata),
 	}
+	c.transform.ApplyVariableSchemas(payload.Variables, req.Payload.Metadata)
+	params, parseErr := c.transform.MetadataParameters(req.Payload.Metadata)
+	if parseErr != nil {
+		err := errors.BadRequest(parseErr.Error())
+		return &pb.CreateTemplateResponse{
+			Metadata: req.Metadata,
+			Result:   &pb.Result{Code: pb.ResultCode(err.GetCode()), Message: err.Error()},
+		}, nil
+	}
+	payload.Parameters = params
 
 	template, err := c.usecase.CreateTemplate(ctx, payload)
 	if err != nil {
@@ -75,9 +121,9 @@ func (c *promptController) GetTemplate(ctx context.Context, req *pb.GetTemplateR
 }
 
 func (c *promptController) ListTemplates(ctx context.Context, req *pb.ListTemplatesRequest) (*pb.ListTemplatesResponse, error) {
+	// The proto has no tag filter; tag queries go through /prompts/templates/search
 	filter := &entities.TemplateFilter{
-		Status: entities.TemplateStatus(req.Status.String()),
-		// Tags:     req.Tags,
+		Status:   c.transform.Pb2Status(req.Status),
 		Page:     req.Page,
 		PageSize: req.PageSize,
 	}
@@ -105,10 +151,22 @@ func (c *promptController) ListTemplates(ctx context.Context, req *pb.ListTempla
 
 func (c *promptController) UpdateTemplate(ctx context.Context, req *pb.UpdateTemplateRequest) (*pb.UpdateTemplateResponse, error) {
 	payload := &entities.UpdateTemplatePayload{
-		ID:        req.Payload.Id,
-		Content:   req.Payload.Template,
-		Variables: c.transform.Pb2Variable(req.Payload.Variables),
-		Status:    entities.TemplateStatus(req.Payload.Status.String()),
+		ID:      req.Payload.Id,
+		Content: req.Payload.Template,
+		Status:  c.transform.Pb2Status(req.Payload.Status),
+		Actor:   entities.RequestFromContext(ctx).Actor,
+	}
+	// Omitted variables keep the current ones instead of creating a new version
+	if len(req.Payload.Variables) > 0 {
+		current, err := c.usecase.GetTemplate(ctx, req.Payload.Id)
+		if err != nil {
+			return &pb.UpdateTemplateResponse{
+				Metadata: req.Metadata,
+				Result:   &pb.Result{Code: pb.ResultCode(err.GetCode()), Message: err.Error()},
+			}, nil
+		}
+		payload.Variables = c.transform.Pb2Variable(req.Payload.Variables)
+		c.transform.KeepStoredVariables(payload.Variables, current.Variables)
 	}
 
 	template, err := c.usecase.UpdateTemplate(ctx, payload)
@@ -127,27 +185,76 @@ func (c *promptController) UpdateTemplate(ctx context.Context, req *pb.UpdateTem
 }
 
 func (c *promptController) DeleteTemplate(ctx context.Context, req *pb.DeleteTemplateRequest) (*pb.ResponseEmpty, error) {
-	_ = c.usecase.DeleteTemplate(ctx, req.Id)
+	if err := c.usecase.DeleteTemplate(ctx, req.Id); err != nil {
+		return &pb.ResponseEmpty{
+			Metadata: req.Metadata,
+			Result:   &pb.Result{Code: pb.ResultCode(err.GetCode()), Message: err.Error()},
+		}, nil
+	}
 	return &pb.ResponseEmpty{
-		Result: &pb.Result{Code: pb.ResultCode_SUCCESS},
+		Metadata: req.Metadata,
+		Result:   &pb.Result{Code: pb.ResultCode_SUCCESS},
 	}, nil
 }
 
 func (c *promptController) RenderTemplate(ctx context.Context, req *pb.RenderTemplateRequest) (*pb.RenderTemplateResponse, error) {
-	rendered, err := c.usecase.RenderTemplate(ctx, req.Payload.TemplateId, req.Payload.Variables)
+	// The proto has no locale; it comes from the X-Locale or Accept-Language header
+	opts := &entities.RenderOptions{Locale: incomingLocale(ctx)}
+	rendered, err := c.usecase.RenderTemplateWithOptions(ctx, req.Payload.TemplateId, req.Payload.Variables, opts)
 	if err != nil {
 		return &pb.RenderTemplateResponse{
 			Metadata: req.Metadata,
 			Result:   &pb.Result{Code: pb.ResultCode(err.GetCode()), Message: err.Error()},
 		}, nil
 	}
+	// Budget warnings have no field of their own in the proto
 	return &pb.RenderTemplateResponse{
 		Metadata: req.Metadata,
-		Result:   &pb.Result{Code: pb.ResultCode_SUCCESS},
+		Result:   &pb.Result{Code: pb.ResultCode_SUCCESS, Message: strings.Join(rendered.Warnings, "; ")},
 		Rendered: &pb.RenderedPrompt{
 			TemplateId:    req.Payload.TemplateId,
 			RenderedText:  rendered.Content,
 			VariablesUsed: rendered.Variables,
+			VersionUsed:   rendered.Version,
 		},
 	}, nil
 }
+
+// incomingLocale reads the preferred locale from gRPC metadata: an explicit
+// x-locale, else Accept-Language as forwarded by the gateway or sent by a
+// gRPC client.
+func incomingLocale(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get("x-locale"); len(values) > 0 {
+		return values[0]
+	}
+	for _, key := range []string{"grpcgateway-accept-language", "accept-language"} {
+		if values := md.Get(key); len(values) > 0 {
+			return helper.PreferredLocale(values[0])
+		}
+	}
+	return ""
+}
+
+// incomingActor reads the caller identified by the gateway from the
+// x-user-id, x-tenant-id and x-roles metadata
+func incomingActor(ctx context.Context) entities.Actor {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return entities.Actor{}
+	}
+	var actor entities.Actor
+	if values := md.Get("x-user-id"); len(values) > 0 {
+		actor.ID = values[0]
+	}
+	if values := md.Get("x-tenant-id"); len(values) > 0 {
+		actor.TenantID = values[0]
+	}
+	if values := md.Get("x-roles"); len(values) > 0 {
+		actor.Roles = entities.ParseRoles(strings.Join(values, ","))
+	}
+	return actor
+}
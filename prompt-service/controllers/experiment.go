@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	pb "github.com/blcvn/kratos-proto/go/prompt"
+)
+
+type iExperimentUsecase interface {
+	CreateExperiment(ctx context.Context, payload *entities.CreateExperimentPayload) (*entities.PromptExperiment, errors.BaseError)
+	GetExperiment(ctx context.Context, id string) (*entities.PromptExperiment, errors.BaseError)
+	ListExperiments(ctx context.Context, filter *entities.ExperimentFilter) ([]*entities.PromptExperiment, int64, errors.BaseError)
+	UpdateExperiment(ctx context.Context, payload *entities.UpdateExperimentPayload) (*entities.PromptExperiment, errors.BaseError)
+	DeleteExperiment(ctx context.Context, id string) errors.BaseError
+	StartExperiment(ctx context.Context, id string) (*entities.PromptExperiment, errors.BaseError)
+	StopExperiment(ctx context.Context, id string) (*entities.PromptExperiment, errors.BaseError)
+	CompleteExperiment(ctx context.Context, id string, winner string) (*entities.PromptExperiment, errors.BaseError)
+	ResolveExperiment(ctx context.Context, id string, subjectKey string) (*entities.ExperimentResolution, errors.BaseError)
+	RecordOutcome(ctx context.Context, payload *entities.RecordOutcomePayload) (*entities.ExperimentOutcome, errors.BaseError)
+	GetExperimentResults(ctx context.Context, id string) (*entities.ExperimentResults, errors.BaseError)
+}
+
+// CreateExperiment creates a running A/B experiment between two templates
+func (c *promptController) CreateExperiment(ctx context.Context, req *pb.CreateExperimentRequest) (*pb.CreateExperimentResponse, error) {
+	payload := &entities.CreateExperimentPayload{
+		Name:     req.Payload.Name,
+		Variants: c.transform.Pb2ExperimentVariants(req.Payload),
+		Start:    true,
+	}
+
+	experiment, err := c.experiments.CreateExperiment(ctx, payload)
+	if err != nil {
+		return &pb.CreateExperimentResponse{
+			Metadata: req.Metadata,
+			Result:   &pb.Result{Code: pb.ResultCode(err.GetCode()), Message: err.Error()},
+		}, nil
+	}
+
+	return &pb.CreateExperimentResponse{
+		Metadata:   req.Metadata,
+		Result:     &pb.Result{Code: pb.ResultCode_SUCCESS, Message: "created successfully"},
+		Experiment: c.transform.Experiment2Pb(experiment),
+	}, nil
+}
+
+func (c *promptController) GetExperiment(ctx context.Context, req *pb.GetExperimentRequest) (*pb.GetExperimentResponse, error) {
+	experiment, err := c.experiments.GetExperiment(ctx, req.Id)
+	if err != nil {
+		return &pb.GetExperimentResponse{
+			Metadata: req.Metadata,
+			Result:   &pb.Result{Code: pb.ResultCode(err.GetCode()), Message: err.Error()},
+		}, nil
+	}
+	return &pb.GetExperimentResponse{
+		Metadata:   req.Metadata,
+		Result:     &pb.Result{Code: pb.ResultCode_SUCCESS},
+		Experiment: c.transform.Experiment2Pb(experiment),
+	}, nil
+}
+
+func (c *promptController) CompleteExperiment(ctx context.Context, req *pb.CompleteExperimentRequest) (*pb.CompleteExperimentResponse, error) {
+	// The proto names the winner by template; only an unambiguous one maps
+	// to a variant key
+	winner := req.GetPayload().GetWinnerId()
+	if winner != "" {
+		current, err := c.experiments.GetExperiment(ctx, req.Id)
+		if err == nil {
+			var keyErr error
+			if winner, keyErr = c.transform.WinnerVariantKey(current, winner); keyErr != nil {
+				err = errors.BadRequest(keyErr.Error())
+			}
+		}
+		if err != nil {
+			return &pb.CompleteExperimentResponse{
+				Metadata: req.Metadata,
+				Result:   &pb.Result{Code: pb.ResultCode(err.GetCode()), Message: err.Error()},
+			}, nil
+		}
+	}
+	experiment, err := c.experiments.CompleteExperiment(ctx, req.Id, winner)
+	if err != nil {
+		return &pb.CompleteExperimentResponse{
+			Metadata: req.Metadata,
+			Result:   &pb.Result{Code: pb.ResultCode(err.GetCode()), Message: err.Error()},
+		}, nil
+	}
+	return &pb.CompleteExperimentResponse{
+		Metadata:   req.Metadata,
+		Result:     &pb.Result{Code: pb.ResultCode_SUCCESS},
+		Experiment: c.transform.Experiment2Pb(experiment),
+	}, nil
+}
+
+// experimentRoutes covers the multi-variant experiment API. New experiments
+// are created as drafts so their variants can be reviewed before starting.
+func (c *promptController) experimentRoutes() []httpRoute {
+	return []httpRoute{
+		{http.MethodGet, "/prompts/experiments", c.listExperiments},
+		{http.MethodPost, "/prompts/experiments/draft", c.createExperiment},
+		{http.MethodGet, "/prompts/experiments/{id}/variants", c.getExperiment},
+		{http.MethodPut, "/prompts/experiments/{id}", c.updateExperiment},
+		{http.MethodDelete, "/prompts/experiments/{id}", c.deleteExperiment},
+		{http.MethodPost, "/prompts/experiments/{id}/start", c.startExperiment},
+		{http.MethodPost, "/prompts/experiments/{id}/stop", c.stopExperiment},
+		{http.MethodPost, "/prompts/experiments/{id}/resolve", c.resolveExperiment},
+		{http.MethodPost, "/prompts/experiments/{id}/outcomes", c.recordOutcome},
+		{http.MethodGet, "/prompts/experiments/{id}/results", c.getExperimentResults},
+	}
+}
+
+func (c *promptController) listExperiments(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("pageSize"))
+	filter := &entities.ExperimentFilter{
+		Status:   entities.ExperimentStatus(query.Get("status")),
+		Page:     int32(page),
+		PageSize: int32(pageSize),
+	}
+
+	experiments, total, err := c.experiments.ListExperiments(r.Context(), filter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"experiments": experiments, "total": total})
+}
+
+func (c *promptController) createExperiment(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var payload entities.CreateExperimentPayload
+	if err := readJSON(r, &payload); err != nil {
+		writeError(w, err)
+		return
+	}
+	c.writeExperiment(w)(c.experiments.CreateExperiment(r.Context(), &payload))
+}
+
+func (c *promptController) getExperiment(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	c.writeExperiment(w)(c.experiments.GetExperiment(r.Context(), params["id"]))
+}
+
+func (c *promptController) updateExperiment(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var payload entities.UpdateExperimentPayload
+	if err := readJSON(r, &payload); err != nil {
+		writeError(w, err)
+		return
+	}
+	payload.ID = params["id"]
+	c.writeExperiment(w)(c.experiments.UpdateExperiment(r.Context(), &payload))
+}
+
+func (c *promptController) deleteExperiment(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if err := c.experiments.DeleteExperiment(r.Context(), params["id"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{})
+}
+
+func (c *promptController) startExperiment(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	c.writeExperiment(w)(c.experiments.StartExperiment(r.Context(), params["id"]))
+}
+
+func (c *promptController) stopExperiment(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	c.writeExperiment(w)(c.experiments.StopExperiment(r.Context(), params["id"]))
+}
+
+func (c *promptController) resolveExperiment(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body struct {
+		SubjectKey string `json:"subjectKey"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resolution, err := c.experiments.ResolveExperiment(r.Context(), params["id"], body.SubjectKey)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"resolution": resolution})
+}
+
+func (c *promptController) recordOutcome(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var payload entities.RecordOutcomePayload
+	if err := readJSON(r, &payload); err != nil {
+		writeError(w, err)
+		return
+	}
+	payload.ExperimentID = params["id"]
+
+	outcome, err := c.experiments.RecordOutcome(r.Context(), &payload)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"outcome": outcome})
+}
+
+func (c *promptController) getExperimentResults(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	results, err := c.experiments.GetExperimentResults(r.Context(), params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"results": results})
+}
+
+func (c *promptController) writeExperiment(w http.ResponseWriter) func(*entities.PromptExperiment, errors.BaseError) {
+	return func(experiment *entities.PromptExperiment, err errors.BaseError) {
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeSuccess(w, map[string]interface{}{"experiment": experiment})
+	}
+}
@@ -0,0 +1,489 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/blcvn/backend/services/prompt-service/helper"
+	pb "github.com/blcvn/kratos-proto/go/prompt"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+// httpRoute is an endpoint served directly on the gateway mux. These cover
+// operations that are not (yet) part of the PromptService proto contract.
+type httpRoute struct {
+	method  string
+	pattern string
+	handler runtime.HandlerFunc
+}
+
+// RegisterHTTPRoutes mounts the JSON endpoints on the grpc-gateway mux
+func (c *promptController) RegisterHTTPRoutes(mux *runtime.ServeMux) error {
+	routes := []httpRoute{
+		{http.MethodGet, "/prompts/templates/{id}/versions", c.listTemplateVersions},
+		{http.MethodGet, "/prompts/templates/{id}/versions/{version}", c.getTemplateVersion},
+		{http.MethodPost, "/prompts/templates/{id}/versions/{version}/rollback", c.rollbackTemplate},
+		{http.MethodGet, "/prompts/templates/{id}/diff", c.diffTemplateVersions},
+		{http.MethodGet, "/prompts/templates/{id}/dependents", c.listTemplateDependents},
+		{http.MethodPost, "/prompts/templates/{id}/review", c.reviewTemplate},
+		{http.MethodGet, "/prompts/templates/{id}/reviews", c.listTemplateReviews},
+		{http.MethodGet, "/prompts/templates/search", c.searchTemplates},
+		{http.MethodPost, "/prompts/templates/lint", c.lintTemplate},
+		{http.MethodPut, "/prompts/templates/{id}/tags", c.updateTemplateTags},
+		{http.MethodPut, "/prompts/templates/{id}/parameters", c.updateTemplateParameters},
+		{http.MethodGet, "/prompts/tags", c.listTags},
+		{http.MethodGet, "/prompts/templates/{id}/labels", c.listTemplateLabels},
+		{http.MethodPut, "/prompts/templates/{id}/labels/{label}", c.setTemplateLabel},
+		{http.MethodDelete, "/prompts/templates/{id}/labels/{label}", c.deleteTemplateLabel},
+		{http.MethodPost, "/prompts/render/messages", c.renderMessages},
+	}
+	routes = append(routes, c.experimentRoutes()...)
+	routes = append(routes, httpRoute{http.MethodGet, "/prompts/audit", c.listAuditEvents})
+
+	for _, route := range routes {
+		if err := mux.HandlePath(route.method, route.pattern, withRequest(route)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withRequest attaches the caller and the route to the request context, as
+// RequestInterceptor does for gRPC calls
+func withRequest(route httpRoute) runtime.HandlerFunc {
+	operation := route.method + " " + route.pattern
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		ctx := entities.ContextWithRequest(r.Context(), entities.RequestInfo{
+			Actor:     entities.RequestFromContext(r.Context()).Actor,
+			Operation: operation,
+		})
+		route.handler(w, r.WithContext(ctx), params)
+	}
+}
+
+// RequestMiddleware attaches the caller, identified by headers, and the
+// route to the context of gateway requests served in-process, which bypass
+// RequestInterceptor
+func RequestMiddleware(headers IdentityHeaders) runtime.Middleware {
+	return func(next runtime.HandlerFunc) runtime.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+			pattern, ok := runtime.HTTPPathPattern(r.Context())
+			if !ok {
+				pattern = r.URL.Path
+			}
+			ctx := entities.ContextWithRequest(r.Context(), entities.RequestInfo{
+				Actor:     headers.actor(r),
+				Operation: r.Method + " " + pattern,
+			})
+			next(w, r.WithContext(ctx), params)
+		}
+	}
+}
+
+func (c *promptController) listTemplateVersions(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	versions, err := c.usecase.ListTemplateVersions(r.Context(), params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"versions": versions})
+}
+
+func (c *promptController) getTemplateVersion(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	version, err := c.usecase.GetTemplateVersion(r.Context(), params["id"], params["version"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"version": version})
+}
+
+// diffTemplateVersions compares two revisions of a template, e.g.
+// ?from=v2&to=production. Without to the latest content is compared; without
+// from, the version before to.
+func (c *promptController) diffTemplateVersions(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	query := r.URL.Query()
+	diff, err := c.usecase.DiffTemplateVersions(r.Context(), params["id"], query.Get("from"), query.Get("to"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"diff": diff})
+}
+
+func (c *promptController) rollbackTemplate(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	template, err := c.usecase.RollbackTemplate(r.Context(), params["id"], params["version"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"template": template})
+}
+
+func (c *promptController) listTemplateDependents(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	dependents, err := c.usecase.ListTemplateDependents(r.Context(), params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"dependents": dependents})
+}
+
+// reviewTemplate moves a template through the review workflow, e.g.
+// {"action": "reject", "comment": "tone is too formal"}. The caller is read
+// from the identity headers; approving and rejecting need a reviewer role.
+func (c *promptController) reviewTemplate(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body struct {
+		Action  entities.TemplateReviewAction `json:"action"`
+		Comment string                        `json:"comment"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	template, err := c.usecase.ReviewTemplate(r.Context(), &entities.ReviewTemplatePayload{
+		ID:      params["id"],
+		Action:  body.Action,
+		Comment: body.Comment,
+		Actor:   entities.RequestFromContext(r.Context()).Actor,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"template": template})
+}
+
+func (c *promptController) listTemplateReviews(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	reviews, err := c.usecase.ListTemplateReviews(r.Context(), params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"reviews": reviews})
+}
+
+// listAuditEvents queries the audit log, newest first, e.g.
+// ?resourceType=template&resourceId=<id>&since=2026-01-01T00:00:00Z. It also
+// filters by actorId, tenantId, action and until, and pages with page and
+// pageSize.
+func (c *promptController) listAuditEvents(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("pageSize"))
+	filter := &entities.AuditFilter{
+		ResourceType: query.Get("resourceType"),
+		ResourceID:   query.Get("resourceId"),
+		ActorID:      query.Get("actorId"),
+		TenantID:     query.Get("tenantId"),
+		Action:       entities.AuditAction(query.Get("action")),
+		Page:         int32(page),
+		PageSize:     int32(pageSize),
+	}
+	for key, dst := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := query.Get(key); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				writeError(w, errors.BadRequest(fmt.Sprintf("invalid %s: %s", key, value)))
+				return
+			}
+			*dst = &t
+		}
+	}
+
+	events, total, err := c.audit.ListAuditEvents(r.Context(), filter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"events": events, "total": total})
+}
+
+// searchTemplates lists templates filtered by status, locale and tags, e.g.
+// ?tags=analysis,vi&tagMode=any. Tags may also be repeated. Each template
+// lists the supported locales its name has no variant in. With a full-text
+// query (?q=moscow) the matches are returned as ranked hits with snippets.
+func (c *promptController) searchTemplates(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("pageSize"))
+
+	var tags []string
+	for _, value := range query["tags"] {
+		tags = append(tags, strings.Split(value, helper.TagSeparator)...)
+	}
+	filter := &entities.TemplateFilter{
+		Query:    query.Get("q"),
+		Status:   entities.TemplateStatus(query.Get("status")),
+		Tags:     tags,
+		TagMode:  entities.TagMatchMode(query.Get("tagMode")),
+		Locale:   query.Get("locale"),
+		Page:     int32(page),
+		PageSize: int32(pageSize),
+	}
+
+	if filter.Query != "" {
+		hits, total, err := c.usecase.SearchTemplates(r.Context(), filter)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeSuccess(w, map[string]interface{}{"hits": hits, "total": total})
+		return
+	}
+
+	templates, total, err := c.usecase.ListTemplates(r.Context(), filter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"templates": templates, "total": total})
+}
+
+// lintTemplate reports undeclared and unused variables and syntax errors in
+// template content before it is saved. The body takes the same fields as a
+// template: {"content", "syntax", "kind", "messages", "variables"}, an
+// optional "name" used to detect include cycles and the "locale" partials are
+// resolved in.
+func (c *promptController) lintTemplate(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		Name      string                     `json:"name"`
+		Locale    string                     `json:"locale"`
+		Content   string                     `json:"content"`
+		Syntax    entities.TemplateSyntax    `json:"syntax"`
+		Kind      entities.TemplateKind      `json:"kind"`
+		Messages  []entities.TemplateMessage `json:"messages"`
+		Variables []entities.Variable        `json:"variables"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	report, err := c.usecase.LintTemplate(r.Context(), &entities.LintTemplatePayload{
+		Name:      body.Name,
+		Locale:    body.Locale,
+		Content:   body.Content,
+		Syntax:    body.Syntax,
+		Kind:      body.Kind,
+		Messages:  body.Messages,
+		Variables: body.Variables,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"valid": report.Valid, "issues": report.Issues})
+}
+
+// updateTemplateTags replaces the tags of a template; the proto update
+// message has no field for them.
+func (c *promptController) updateTemplateTags(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body struct {
+		Tags []string `json:"tags"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		writeError(w, err)
+		return
+	}
+	if body.Tags == nil {
+		body.Tags = []string{}
+	}
+
+	template, err := c.usecase.UpdateTemplate(r.Context(), &entities.UpdateTemplatePayload{
+		ID:   params["id"],
+		Tags: body.Tags,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"template": template})
+}
+
+// updateTemplateParameters replaces the model parameters of a template,
+// recording a new version. An empty object clears them.
+func (c *promptController) updateTemplateParameters(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body entities.ModelParameters
+	if err := readJSON(r, &body); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	template, err := c.usecase.UpdateTemplate(r.Context(), &entities.UpdateTemplatePayload{
+		ID:         params["id"],
+		Parameters: &body,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"template": template})
+}
+
+func (c *promptController) listTags(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	tags, err := c.usecase.ListTags(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"tags": tags})
+}
+
+func (c *promptController) listTemplateLabels(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	labels, err := c.usecase.ListTemplateLabels(r.Context(), params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"labels": labels})
+}
+
+// setTemplateLabel points a label at a version, e.g. promoting staging to
+// production with {"version": "v7", "from": "v6"}. The optional "from" makes
+// the move fail if someone else moved the label in the meantime.
+func (c *promptController) setTemplateLabel(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body struct {
+		Version string `json:"version"`
+		From    string `json:"from"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	label, err := c.usecase.SetTemplateLabel(r.Context(), params["id"], params["label"], body.Version, body.From)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{"label": label})
+}
+
+func (c *promptController) deleteTemplateLabel(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if err := c.usecase.DeleteTemplateLabel(r.Context(), params["id"], params["label"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{})
+}
+
+// renderMessages renders a template like RenderTemplate but returns chat
+// templates as a structured message list rather than encoded text. Text
+// templates come back as a single user message. With a provider, e.g.
+// {"provider": "anthropic"}, the response also carries the prompt shaped as
+// that provider's request body, with the given parameters overriding the
+// template's. "countTokens" adds a token estimate even without a budget.
+// "locale" picks the variant, defaulting to the X-Locale or Accept-Language
+// header.
+func (c *promptController) renderMessages(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		TemplateID  string                    `json:"templateId"`
+		Variables   map[string]string         `json:"variables"`
+		Provider    entities.Provider         `json:"provider"`
+		Parameters  *entities.ModelParameters `json:"parameters"`
+		CountTokens bool                      `json:"countTokens"`
+		Locale      string                    `json:"locale"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var (
+		rendered *entities.RenderedPrompt
+		request  interface{}
+		err      errors.BaseError
+	)
+	if body.Locale == "" {
+		body.Locale = requestLocale(r)
+	}
+	opts := &entities.RenderOptions{CountTokens: body.CountTokens, Parameters: body.Parameters, Locale: body.Locale}
+	if body.Provider != "" {
+		rendered, request, err = c.usecase.RenderProviderRequest(r.Context(), body.TemplateID, body.Variables, body.Provider, opts)
+	} else {
+		rendered, err = c.usecase.RenderTemplateWithOptions(r.Context(), body.TemplateID, body.Variables, opts)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if rendered.Messages == nil {
+		rendered.Messages = []entities.RenderedMessage{{Role: entities.RoleUser, Content: rendered.Content}}
+	}
+	rendered.Content = ""
+	response := map[string]interface{}{"prompt": rendered}
+	if request != nil {
+		response["request"] = request
+	}
+	writeSuccess(w, response)
+}
+
+// requestLocale reads the preferred locale from the X-Locale header, else
+// from Accept-Language
+func requestLocale(r *http.Request) string {
+	if locale := r.Header.Get("X-Locale"); locale != "" {
+		return locale
+	}
+	return helper.PreferredLocale(r.Header.Get("Accept-Language"))
+}
+
+// IdentityHeaders names the headers Kong identifies the caller with
+type IdentityHeaders struct {
+	UserID   string
+	TenantID string
+	Roles    string
+}
+
+// DefaultIdentityHeaders are the headers used when none are configured
+var DefaultIdentityHeaders = IdentityHeaders{UserID: "X-User-ID", TenantID: "X-Tenant-ID", Roles: "X-Roles"}
+
+// actor reads the caller identified by the gateway
+func (h IdentityHeaders) actor(r *http.Request) entities.Actor {
+	return entities.Actor{
+		ID:       r.Header.Get(h.UserID),
+		TenantID: r.Header.Get(h.TenantID),
+		Roles:    entities.ParseRoles(r.Header.Get(h.Roles)),
+	}
+}
+
+// httpResult mirrors pb.Result so JSON clients see the same envelope as the
+// gateway-generated endpoints.
+type httpResult struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func writeSuccess(w http.ResponseWriter, body map[string]interface{}) {
+	body["result"] = httpResult{Code: pb.ResultCode_SUCCESS.String()}
+	writeJSON(w, http.StatusOK, body)
+}
+
+func writeError(w http.ResponseWriter, err errors.BaseError) {
+	writeJSON(w, int(err.GetCode()), map[string]interface{}{
+		"result": httpResult{Code: pb.ResultCode(err.GetCode()).String(), Message: err.Error()},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// readJSON decodes the request body into dst. An empty body leaves dst as is.
+func readJSON(r *http.Request, dst interface{}) errors.BaseError {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil && err != io.EOF {
+		return errors.BadRequest("invalid request body: " + err.Error())
+	}
+	return nil
+}
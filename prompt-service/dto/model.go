@@ -7,16 +7,23 @@ import (
 	"gorm.io/gorm"
 )
 
-// PromptTemplate represents the database model for prompt templates
+// PromptTemplate represents the database model for prompt templates.
+// The table also has a generated search_vector column used for full-text
+// search; it is maintained by Postgres and deliberately not mapped here.
 type PromptTemplate struct {
 	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	Name        string    `gorm:"type:varchar(255);uniqueIndex;not null"`
+	TenantID    string    `gorm:"type:varchar(255);uniqueIndex:idx_prompt_templates_tenant_name_locale;not null;default:''"` // '' for global templates
+	Name        string    `gorm:"type:varchar(255);uniqueIndex:idx_prompt_templates_tenant_name_locale;not null"`
+	Locale      string    `gorm:"type:varchar(20);uniqueIndex:idx_prompt_templates_tenant_name_locale;not null;default:'vi'"`
 	Description string    `gorm:"type:text"`
 	Version     string    `gorm:"type:varchar(50);default:'v1'"`
 	Content     string    `gorm:"type:text;not null"`
+	Syntax      string    `gorm:"type:varchar(20);default:'go'"`
+	Kind        string    `gorm:"type:varchar(20);default:'text'"`
+	Parameters  string    `gorm:"type:jsonb;default:'{}'"` // JSON object of model parameters
 	Variables   string    `gorm:"type:jsonb;default:'[]'"` // JSON array of variables
 	Tags        string    `gorm:"type:jsonb;default:'[]'"` // JSON array of tags
-	Status      string    `gorm:"type:varchar(50);default:'active';index"`
+	Status      string    `gorm:"type:varchar(50);default:'draft';index"`
 	CreatedAt   time.Time `gorm:"default:now()"`
 	UpdatedAt   time.Time `gorm:"default:now()"`
 }
@@ -29,22 +36,153 @@ func (PromptTemplate) TableName() string {
 // BeforeUpdate hook
 func (t *PromptTemplate) BeforeUpdate(tx *gorm.DB) error {
 	t.UpdatedAt = time.Now()
-	// Logic to increment version could go here
 	return nil
 }
 
+// PromptTemplateVersion represents an immutable revision of a prompt template.
+// Rows are only ever inserted; the template row mirrors the latest one.
+type PromptTemplateVersion struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	TemplateID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_template_versions_number"`
+	VersionNumber int       `gorm:"not null;uniqueIndex:idx_template_versions_number"`
+	Version       string    `gorm:"type:varchar(50);not null"`
+	Content       string    `gorm:"type:text;not null"`
+	Syntax        string    `gorm:"type:varchar(20);default:'go'"`
+	Kind          string    `gorm:"type:varchar(20);default:'text'"`
+	Parameters    string    `gorm:"type:jsonb;default:'{}'"` // JSON object of model parameters
+	Variables     string    `gorm:"type:jsonb;default:'[]'"` // JSON array of variables
+	Tags          *string   `gorm:"type:jsonb"`              // JSON array of tags when recorded, NULL before
+	SourcePath    *string   `gorm:"type:text"`               // file the version was synced from
+	SourceCommit  *string   `gorm:"type:varchar(80)"`        // commit of that file, "-dirty" if modified
+	CreatedAt     time.Time `gorm:"default:now()"`
+}
+
+// TableName specifies the table name
+func (PromptTemplateVersion) TableName() string {
+	return "prompt_template_versions"
+}
+
+// TemplateLabel points a named environment at a template version
+type TemplateLabel struct {
+	TemplateID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Label         string    `gorm:"type:varchar(50);primaryKey"`
+	VersionNumber int       `gorm:"not null"`
+	UpdatedAt     time.Time `gorm:"default:now()"`
+}
+
+// TableName specifies the table name
+func (TemplateLabel) TableName() string {
+	return "prompt_template_labels"
+}
+
+// TemplateReview records a status change of a template. Rows are only ever
+// inserted, so they form the template's review history.
+type TemplateReview struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	TemplateID    uuid.UUID `gorm:"type:uuid;not null;index:idx_template_reviews_template"`
+	VersionNumber int       `gorm:"not null"`
+	Action        string    `gorm:"type:varchar(20);not null"`
+	FromStatus    string    `gorm:"type:varchar(50);not null"`
+	ToStatus      string    `gorm:"type:varchar(50);not null"`
+	Actor         string    `gorm:"type:varchar(255)"`
+	Comment       string    `gorm:"type:text"`
+	CreatedAt     time.Time `gorm:"default:now();index:idx_template_reviews_template"`
+}
+
+// TableName specifies the table name
+func (TemplateReview) TableName() string {
+	return "prompt_template_reviews"
+}
+
+// AuditEvent is an entry of the audit log. The table rejects updates and
+// deletes, so rows are only ever inserted.
+type AuditEvent struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Action       string    `gorm:"type:varchar(20);not null"`
+	Operation    string    `gorm:"type:varchar(255);not null"`
+	ResourceType string    `gorm:"type:varchar(50);not null;index:idx_audit_log_resource"`
+	ResourceID   string    `gorm:"type:varchar(255);not null;index:idx_audit_log_resource"`
+	ActorID      string    `gorm:"type:varchar(255);index:idx_audit_log_actor"`
+	TenantID     string    `gorm:"type:varchar(255)"`
+	Before       *string   `gorm:"type:jsonb"` // NULL for creates
+	After        *string   `gorm:"type:jsonb"` // NULL for deletes
+	CreatedAt    time.Time `gorm:"default:now();index:idx_audit_log_resource;index:idx_audit_log_actor"`
+}
+
+// TableName specifies the table name
+func (AuditEvent) TableName() string {
+	return "prompt_audit_log"
+}
+
+// TemplateInclude records a partial referenced by the current content of a
+// template, so dependents can be found without parsing every template.
+type TemplateInclude struct {
+	TemplateID     uuid.UUID `gorm:"type:uuid;primaryKey"`
+	PartialName    string    `gorm:"type:varchar(255);primaryKey;index"`
+	PartialVersion string    `gorm:"type:varchar(50);primaryKey;default:''"` // empty when not pinned
+}
+
+// TableName specifies the table name
+func (TemplateInclude) TableName() string {
+	return "prompt_template_includes"
+}
+
 // Experiment represents the database model for experiments
 type Experiment struct {
 	ID               uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
 	Name             string    `gorm:"type:varchar(255);not null"`
+	Description      string    `gorm:"type:text"`
 	PromptTemplateID uuid.UUID `gorm:"type:uuid;not null;index"`
-	ModelID          uuid.UUID `gorm:"type:uuid;not null;index"`
+	ModelID          string    `gorm:"type:varchar(255);index"`
 	Config           string    `gorm:"type:jsonb;default:'{}'"`
-	Status           string    `gorm:"type:varchar(50);default:'active'"`
+	Variants         string    `gorm:"type:jsonb;default:'[]'"` // JSON array of variants
+	HoldoutPercent   int       `gorm:"default:0"`
+	Overrides        string    `gorm:"type:jsonb;default:'{}'"`                     // subject key -> variant key
+	TenantID         string    `gorm:"type:varchar(255);not null;default:'';index"` // '' for global experiments
+	Status           string    `gorm:"type:varchar(50);default:'draft';index"`
+	WinnerVariant    string    `gorm:"type:varchar(100)"`
 	CreatedAt        time.Time `gorm:"default:now()"`
+	UpdatedAt        time.Time `gorm:"default:now()"`
+	StartedAt        *time.Time
+	CompletedAt      *time.Time
 }
 
 // TableName specifies the table name
 func (Experiment) TableName() string {
 	return "prompt_experiments"
 }
+
+// ExperimentAssignment represents the variant a subject was assigned to
+type ExperimentAssignment struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ExperimentID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_assignments_subject"`
+	SubjectKey   string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_assignments_subject"`
+	VariantKey   string    `gorm:"type:varchar(100);not null"`
+	Holdout      bool      `gorm:"default:false"`
+	Forced       bool      `gorm:"default:false"`
+	CreatedAt    time.Time `gorm:"default:now()"`
+	UpdatedAt    time.Time `gorm:"default:now()"`
+}
+
+// TableName specifies the table name
+func (ExperimentAssignment) TableName() string {
+	return "prompt_experiment_assignments"
+}
+
+// ExperimentOutcome represents a result recorded against an assignment
+type ExperimentOutcome struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ExperimentID uuid.UUID `gorm:"type:uuid;not null;index:idx_outcomes_experiment_metric"`
+	AssignmentID uuid.UUID `gorm:"type:uuid;not null;index"`
+	VariantKey   string    `gorm:"type:varchar(100);not null"`
+	Holdout      bool      `gorm:"default:false"`
+	Metric       string    `gorm:"type:varchar(100);not null;index:idx_outcomes_experiment_metric"`
+	Kind         string    `gorm:"type:varchar(20);not null"`
+	Value        float64   `gorm:"not null"`
+	CreatedAt    time.Time `gorm:"default:now()"`
+}
+
+// TableName specifies the table name
+func (ExperimentOutcome) TableName() string {
+	return "prompt_experiment_outcomes"
+}
@@ -0,0 +1,72 @@
+package entities
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// AuditAction is the kind of change an audit event records
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+	AuditStatus AuditAction = "status" // review workflow and experiment lifecycle
+	AuditConfig AuditAction = "config" // render configuration, such as labels
+)
+
+// Audited resource types
+const (
+	AuditResourceTemplate   = "template"
+	AuditResourceLabel      = "label" // ResourceID is the template's
+	AuditResourceExperiment = "experiment"
+)
+
+// AuditEvent is an entry of the append-only audit log: who changed what,
+// through which operation, with the resource as it was before and after
+type AuditEvent struct {
+	ID           string          `json:"id"`
+	Action       AuditAction     `json:"action"`
+	Operation    string          `json:"operation"` // RPC or route, e.g. "/prompt.v1.PromptService/UpdateTemplate"
+	ResourceType string          `json:"resourceType"`
+	ResourceID   string          `json:"resourceId"`
+	ActorID      string          `json:"actorId,omitempty"`
+	TenantID     string          `json:"tenantId,omitempty"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+	CreatedAt    time.Time       `json:"createdAt"`
+}
+
+// AuditFilter filter for listing audit events
+type AuditFilter struct {
+	ResourceType string
+	ResourceID   string
+	ActorID      string
+	TenantID     string
+	Action       AuditAction
+	Since        *time.Time
+	Until        *time.Time
+	Page         int32
+	PageSize     int32
+}
+
+// RequestInfo identifies the request a change is made in
+type RequestInfo struct {
+	Actor     Actor
+	Operation string
+}
+
+type requestInfoKey struct{}
+
+// ContextWithRequest attaches the request info to ctx
+func ContextWithRequest(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestFromContext returns the request info attached to ctx, if any
+func RequestFromContext(ctx context.Context) RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info
+}
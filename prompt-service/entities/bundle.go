@@ -0,0 +1,44 @@
+package entities
+
+// PromptFile is a template as kept in a prompt bundle: a YAML file, or a
+// Markdown file whose frontmatter holds every field but the content
+type PromptFile struct {
+	Path        string            `yaml:"-"` // relative to the bundle directory
+	Source      *TemplateSource   `yaml:"-"` // set when read from a git working tree
+	Name        string            `yaml:"name"`
+	Locale      string            `yaml:"locale,omitempty"`
+	Description string            `yaml:"description,omitempty"`
+	Kind        TemplateKind      `yaml:"kind,omitempty"`
+	Syntax      TemplateSyntax    `yaml:"syntax,omitempty"`
+	Tags        []string          `yaml:"tags,omitempty"`
+	Variables   []Variable        `yaml:"variables,omitempty"`
+	Parameters  *ModelParameters  `yaml:"parameters,omitempty"`
+	Messages    []TemplateMessage `yaml:"messages,omitempty"` // for chat templates, instead of Content
+	Content     string            `yaml:"content,omitempty"`
+}
+
+// ImportAction is what importing a prompt file does to the stored template
+type ImportAction string
+
+const (
+	ImportCreate    ImportAction = "create"
+	ImportUpdate    ImportAction = "update" // records a new version
+	ImportUnchanged ImportAction = "unchanged"
+)
+
+// ImportChange is the outcome of importing one prompt file
+type ImportChange struct {
+	Path    string       `json:"path"`
+	Name    string       `json:"name"`
+	Locale  string       `json:"locale"`
+	Action  ImportAction `json:"action"`
+	Version string       `json:"version,omitempty"` // version after the import; empty on a dry run
+	Diff    string       `json:"diff,omitempty"`    // unified diff from the stored template to the file
+}
+
+// ImportReport lists the outcome of a bundle import, in the order the files
+// were applied
+type ImportReport struct {
+	DryRun  bool           `json:"dryRun"`
+	Changes []ImportChange `json:"changes"`
+}
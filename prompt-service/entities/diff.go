@@ -0,0 +1,45 @@
+package entities
+
+// Kinds of change to a variable
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// TemplateDiff is what changed between two revisions of a template
+type TemplateDiff struct {
+	TemplateID string           `json:"templateId"`
+	From       string           `json:"from"`       // version of the old side
+	To         string           `json:"to"`         // version of the new side
+	Content    string           `json:"content"`    // unified diff of the content, "" if unchanged
+	Fields     []FieldChange    `json:"fields"`     // syntax and kind
+	Variables  []VariableChange `json:"variables"`  // in the order of the new side, then removed ones
+	Parameters []FieldChange    `json:"parameters"` // one per model parameter that differs
+	Tags       *TagChange       `json:"tags,omitempty"`
+}
+
+// FieldChange is a setting with different values on each side; a side
+// without the setting has a nil value
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// VariableChange is a variable that was added, removed or modified. Fields
+// lists the attributes that differ for modified variables.
+type VariableChange struct {
+	Name   string    `json:"name"`
+	Change string    `json:"change"`
+	Fields []string  `json:"fields,omitempty"`
+	From   *Variable `json:"from,omitempty"`
+	To     *Variable `json:"to,omitempty"`
+}
+
+// TagChange lists the tags only on one side. It is omitted from a diff when
+// either version predates tags being recorded with versions.
+type TagChange struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
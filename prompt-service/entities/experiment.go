@@ -0,0 +1,198 @@
+package entities
+
+import (
+	"time"
+)
+
+// ExperimentStatus represents the lifecycle state of a prompt experiment
+type ExperimentStatus string
+
+const (
+	ExperimentStatusDraft     ExperimentStatus = "draft"
+	ExperimentStatusRunning   ExperimentStatus = "running"
+	ExperimentStatusStopped   ExperimentStatus = "stopped"
+	ExperimentStatusCompleted ExperimentStatus = "completed"
+)
+
+// ExperimentVariant is one arm of an experiment: a template revision rendered
+// with a given model and configuration, receiving a share of the traffic.
+type ExperimentVariant struct {
+	Key             string            `json:"key"`
+	Name            string            `json:"name"`
+	TemplateID      string            `json:"templateId"`
+	TemplateVersion string            `json:"templateVersion"` // empty means the template's current version
+	ModelID         string            `json:"modelId"`
+	Config          map[string]string `json:"config"`
+	Weight          int               `json:"weight"`
+}
+
+// PromptExperiment represents an A/B test for prompts
+type PromptExperiment struct {
+	ID               string              `json:"id"`
+	Name             string              `json:"name"`
+	Description      string              `json:"description"`
+	PromptTemplateID string              `json:"promptTemplateId"` // template of the control variant
+	ModelID          string              `json:"modelId"`
+	Config           map[string]string   `json:"config"`
+	Variants         []ExperimentVariant `json:"variants"`
+	HoldoutPercent   int                 `json:"holdoutPercent"`      // share of subjects kept on the control, outside the experiment
+	Overrides        map[string]string   `json:"overrides,omitempty"` // subject key -> forced variant key, for QA
+	TenantID         string              `json:"tenantId,omitempty"`  // owning tenant, empty for global experiments
+	Status           ExperimentStatus    `json:"status"`
+	WinnerVariant    string              `json:"winnerVariant,omitempty"`
+	CreatedAt        time.Time           `json:"createdAt"`
+	UpdatedAt        time.Time           `json:"updatedAt"`
+	StartedAt        *time.Time          `json:"startedAt,omitempty"`
+	CompletedAt      *time.Time          `json:"completedAt,omitempty"`
+}
+
+// Variant returns the variant with the given key
+func (e *PromptExperiment) Variant(key string) (*ExperimentVariant, bool) {
+	for i := range e.Variants {
+		if e.Variants[i].Key == key {
+			return &e.Variants[i], true
+		}
+	}
+	return nil, false
+}
+
+// ExperimentAssignment records which variant a subject was given, so results
+// can be attributed and the subject keeps seeing the same variant.
+type ExperimentAssignment struct {
+	ID           string    `json:"id"`
+	ExperimentID string    `json:"experimentId"`
+	SubjectKey   string    `json:"subjectKey"`
+	VariantKey   string    `json:"variantKey"`
+	Holdout      bool      `json:"holdout"`
+	Forced       bool      `json:"forced"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// ExperimentResolution tells the caller which prompt to render for a subject
+type ExperimentResolution struct {
+	ExperimentID    string            `json:"experimentId"`
+	AssignmentID    string            `json:"assignmentId,omitempty"`
+	VariantKey      string            `json:"variantKey"`
+	TemplateID      string            `json:"templateId"`
+	TemplateName    string            `json:"templateName"`
+	TemplateVersion string            `json:"templateVersion"`
+	ModelID         string            `json:"modelId"`
+	Config          map[string]string `json:"config"`
+	InExperiment    bool              `json:"inExperiment"` // false when the control is served outside a running experiment
+	Holdout         bool              `json:"holdout"`
+	Forced          bool              `json:"forced"`
+}
+
+// CreateExperimentPayload payload for creating an experiment
+type CreateExperimentPayload struct {
+	Name           string              `json:"name"`
+	Description    string              `json:"description"`
+	Variants       []ExperimentVariant `json:"variants"`
+	Config         map[string]string   `json:"config"`
+	HoldoutPercent int                 `json:"holdoutPercent"`
+	Overrides      map[string]string   `json:"overrides"`
+	Start          bool                `json:"start"`
+}
+
+// UpdateExperimentPayload payload for updating an experiment
+type UpdateExperimentPayload struct {
+	ID             string              `json:"id"`
+	Name           string              `json:"name"`
+	Description    string              `json:"description"`
+	Variants       []ExperimentVariant `json:"variants"`
+	Config         map[string]string   `json:"config"`
+	HoldoutPercent *int                `json:"holdoutPercent"`
+	Overrides      map[string]string   `json:"overrides"`
+}
+
+// ExperimentFilter filter for listing experiments
+type ExperimentFilter struct {
+	Status   ExperimentStatus
+	Page     int32
+	PageSize int32
+}
+
+// OutcomeKind describes how an outcome value is interpreted
+type OutcomeKind string
+
+const (
+	// OutcomeKindScore is a numeric score, compared with Welch's t-test
+	OutcomeKindScore OutcomeKind = "score"
+	// OutcomeKindFeedback is a thumbs up (1) or down (0) on a response
+	OutcomeKindFeedback OutcomeKind = "feedback"
+	// OutcomeKindConversion is an event such as "requirement_accepted",
+	// measured against every subject exposed to the variant
+	OutcomeKindConversion OutcomeKind = "conversion"
+)
+
+// ExperimentOutcome is a result observed for an experiment assignment
+type ExperimentOutcome struct {
+	ID           string      `json:"id"`
+	ExperimentID string      `json:"experimentId"`
+	AssignmentID string      `json:"assignmentId"`
+	VariantKey   string      `json:"variantKey"`
+	Metric       string      `json:"metric"`
+	Kind         OutcomeKind `json:"kind"`
+	Value        float64     `json:"value"`
+	CreatedAt    time.Time   `json:"createdAt"`
+}
+
+// RecordOutcomePayload payload for recording an outcome
+type RecordOutcomePayload struct {
+	ExperimentID string      `json:"experimentId"`
+	AssignmentID string      `json:"assignmentId"`
+	Metric       string      `json:"metric"`
+	Kind         OutcomeKind `json:"kind"`
+	Value        *float64    `json:"value"`
+}
+
+// OutcomeAggregate summarizes the outcomes of one metric for one variant
+type OutcomeAggregate struct {
+	Metric     string
+	Kind       OutcomeKind
+	VariantKey string
+	Count      int64   // number of outcomes
+	Converted  int64   // distinct assignments with a positive outcome
+	Sum        float64 // sum of values
+	Mean       float64
+	Variance   float64 // sample variance
+}
+
+// VariantStats are the statistics of a metric for a single variant
+type VariantStats struct {
+	VariantKey string  `json:"variantKey"`
+	SampleSize int64   `json:"sampleSize"`
+	Mean       float64 `json:"mean"` // conversion or thumbs-up rate for binary metrics
+	StdDev     float64 `json:"stdDev"`
+	CILow      float64 `json:"ciLow"`
+	CIHigh     float64 `json:"ciHigh"`
+}
+
+// VariantComparison compares a variant with the control
+type VariantComparison struct {
+	VariantKey       string  `json:"variantKey"`
+	BaselineKey      string  `json:"baselineKey"`
+	Test             string  `json:"test"` // welch_t or two_proportion_z
+	Statistic        float64 `json:"statistic"`
+	DegreesOfFreedom float64 `json:"degreesOfFreedom,omitempty"`
+	PValue           float64 `json:"pValue"`
+	Lift             float64 `json:"lift"` // relative change of the mean versus the baseline
+	Significant      bool    `json:"significant"`
+}
+
+// MetricResults holds the per-variant results of one metric
+type MetricResults struct {
+	Metric      string              `json:"metric"`
+	Kind        OutcomeKind         `json:"kind"`
+	Variants    []VariantStats      `json:"variants"`
+	Comparisons []VariantComparison `json:"comparisons"`
+}
+
+// ExperimentResults aggregates every metric recorded for an experiment
+type ExperimentResults struct {
+	ExperimentID string           `json:"experimentId"`
+	Status       ExperimentStatus `json:"status"`
+	Confidence   float64          `json:"confidence"`
+	Exposures    map[string]int64 `json:"exposures"` // assigned subjects per variant, holdout excluded
+	Metrics      []MetricResults  `json:"metrics"`
+}
@@ -0,0 +1,67 @@
+package entities
+
+import "fmt"
+
+// LintSeverity tells whether a lint issue blocks saving a template
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+)
+
+// Lint issue codes
+const (
+	LintCodeSyntax     = "syntax"
+	LintCodeInclude    = "include"
+	LintCodeUndeclared = "undeclared-variable"
+	LintCodeUnused     = "unused-variable"
+)
+
+// LintIssue is a problem found in template content. Line and Column are
+// 1-based and 0 when unknown; Message is the 1-based message of a chat
+// template the position refers to.
+type LintIssue struct {
+	Severity LintSeverity `json:"severity"`
+	Code     string       `json:"code"`
+	Text     string       `json:"text"`
+	Variable string       `json:"variable,omitempty"`
+	Message  int          `json:"message,omitempty"`
+	Line     int          `json:"line,omitempty"`
+	Column   int          `json:"column,omitempty"`
+}
+
+func (i LintIssue) String() string {
+	location := ""
+	if i.Message > 0 {
+		location = fmt.Sprintf("message %d, ", i.Message)
+	}
+	if i.Line > 0 {
+		location += fmt.Sprintf("line %d", i.Line)
+		if i.Column > 0 {
+			location += fmt.Sprintf(", col %d", i.Column)
+		}
+	}
+	if location == "" {
+		return i.Text
+	}
+	return fmt.Sprintf("%s: %s", location, i.Text)
+}
+
+// LintReport is the result of linting a template
+type LintReport struct {
+	Valid  bool        `json:"valid"` // no issue is an error
+	Issues []LintIssue `json:"issues"`
+}
+
+// LintTemplatePayload is template content to lint before it is saved. Name
+// is optional and only used to detect include cycles.
+type LintTemplatePayload struct {
+	Name      string
+	Locale    string // partials are resolved in this locale
+	Content   string
+	Syntax    TemplateSyntax
+	Kind      TemplateKind
+	Messages  []TemplateMessage // for chat templates, instead of Content
+	Variables []Variable
+}
@@ -0,0 +1,91 @@
+package entities
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// TemplateKind distinguishes plain text templates from chat templates
+type TemplateKind string
+
+const (
+	// TemplateKindText is a single block of text
+	TemplateKindText TemplateKind = "text"
+	// TemplateKindChat is an ordered list of role-tagged messages, stored in
+	// Content as a JSON array
+	TemplateKindChat TemplateKind = "chat"
+)
+
+// IsValid reports whether the kind is supported
+func (k TemplateKind) IsValid() bool {
+	return k == TemplateKindText || k == TemplateKindChat
+}
+
+// Chat message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// TemplateMessage is one message of a chat template. Its content is a
+// template in the syntax of the whole template.
+type TemplateMessage struct {
+	Role    string `json:"role" yaml:"role"`
+	Name    string `json:"name,omitempty" yaml:"name,omitempty"`
+	Content string `json:"content" yaml:"content"`
+	Example bool   `json:"example,omitempty" yaml:"example,omitempty"` // part of a few-shot example turn
+}
+
+// RenderedMessage is a chat message after rendering
+type RenderedMessage struct {
+	Role    string `json:"role"`
+	Name    string `json:"name,omitempty"`
+	Content string `json:"content"`
+	Example bool   `json:"example,omitempty"`
+}
+
+// ParseMessages decodes the content of a chat template
+func ParseMessages(content string) ([]TemplateMessage, error) {
+	var messages []TemplateMessage
+	if err := json.Unmarshal([]byte(content), &messages); err != nil {
+		return nil, fmt.Errorf("chat template content must be a JSON array of messages: %w", err)
+	}
+	return messages, nil
+}
+
+// EncodeMessages returns the stored form of chat template messages
+func EncodeMessages(messages []TemplateMessage) string {
+	b, _ := json.Marshal(messages)
+	return string(b)
+}
+
+// ValidateMessages checks roles and ordering: system messages come first,
+// and few-shot examples are user or assistant turns.
+func ValidateMessages(messages []TemplateMessage) []string {
+	if len(messages) == 0 {
+		return []string{"a chat template needs at least one message"}
+	}
+
+	var errs []string
+	seenConversation := false
+	for i, m := range messages {
+		switch m.Role {
+		case RoleSystem:
+			if seenConversation {
+				errs = append(errs, fmt.Sprintf("message %d: system messages must come before user and assistant messages", i+1))
+			}
+			if m.Example {
+				errs = append(errs, fmt.Sprintf("message %d: only user and assistant messages can be examples", i+1))
+			}
+		case RoleUser, RoleAssistant:
+			seenConversation = true
+		default:
+			errs = append(errs, fmt.Sprintf("message %d: unknown role %q", i+1, m.Role))
+		}
+		if m.Content == "" {
+			errs = append(errs, fmt.Sprintf("message %d: content is required", i+1))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,136 @@
+package entities
+
+import "fmt"
+
+// Provider names an LLM API whose request format a prompt can be rendered to
+type Provider string
+
+const (
+	ProviderOpenAI    Provider = "openai"
+	ProviderAnthropic Provider = "anthropic"
+	ProviderGemini    Provider = "gemini"
+)
+
+// IsValid reports whether the provider is supported
+func (p Provider) IsValid() bool {
+	return p == ProviderOpenAI || p == ProviderAnthropic || p == ProviderGemini
+}
+
+// Response formats a model can be asked to produce
+const (
+	ResponseFormatText = "text"
+	ResponseFormatJSON = "json"
+)
+
+// What to do when a rendered prompt exceeds MaxInputTokens
+const (
+	BudgetActionReject = "reject"
+	BudgetActionWarn   = "warn"
+)
+
+// ModelParameters are the model settings sent along with a prompt. Unset
+// fields are left to the provider's defaults. MaxInputTokens and BudgetAction
+// bound the rendered prompt itself and are not sent to the provider.
+type ModelParameters struct {
+	Model          string   `json:"model,omitempty" yaml:"model,omitempty"`
+	Temperature    *float64 `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+	TopP           *float64 `json:"topP,omitempty" yaml:"topP,omitempty"`
+	MaxTokens      *int     `json:"maxTokens,omitempty" yaml:"maxTokens,omitempty"`
+	Stop           []string `json:"stop,omitempty" yaml:"stop,omitempty"`
+	ResponseFormat string   `json:"responseFormat,omitempty" yaml:"responseFormat,omitempty"`
+	MaxInputTokens *int     `json:"maxInputTokens,omitempty" yaml:"maxInputTokens,omitempty"`
+	BudgetAction   string   `json:"budgetAction,omitempty" yaml:"budgetAction,omitempty"` // reject (default) or warn
+}
+
+// Merge returns the parameters with every field set in override replacing
+// the current value. Either side may be nil.
+func (p *ModelParameters) Merge(override *ModelParameters) *ModelParameters {
+	merged := &ModelParameters{}
+	if p != nil {
+		*merged = *p
+	}
+	if override == nil {
+		return merged
+	}
+	if override.Model != "" {
+		merged.Model = override.Model
+	}
+	if override.Temperature != nil {
+		merged.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		merged.TopP = override.TopP
+	}
+	if override.MaxTokens != nil {
+		merged.MaxTokens = override.MaxTokens
+	}
+	if override.Stop != nil {
+		merged.Stop = override.Stop
+	}
+	if override.ResponseFormat != "" {
+		merged.ResponseFormat = override.ResponseFormat
+	}
+	if override.MaxInputTokens != nil {
+		merged.MaxInputTokens = override.MaxInputTokens
+	}
+	if override.BudgetAction != "" {
+		merged.BudgetAction = override.BudgetAction
+	}
+	return merged
+}
+
+const (
+	// maxStopSequences is the most stop sequences every provider accepts
+	maxStopSequences = 4
+	// maxModelIDLength bounds the length of a model ID
+	maxModelIDLength = 100
+)
+
+// Validate checks the parameters against the ranges the providers accept
+func (p *ModelParameters) Validate() []string {
+	if p == nil {
+		return nil
+	}
+
+	var errs []string
+	if len(p.Model) > maxModelIDLength {
+		errs = append(errs, fmt.Sprintf("model must be at most %d characters", maxModelIDLength))
+	}
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2) {
+		errs = append(errs, "temperature must be between 0 and 2")
+	}
+	if p.TopP != nil && (*p.TopP <= 0 || *p.TopP > 1) {
+		errs = append(errs, "topP must be greater than 0 and at most 1")
+	}
+	if p.MaxTokens != nil && *p.MaxTokens <= 0 {
+		errs = append(errs, "maxTokens must be positive")
+	}
+	if len(p.Stop) > maxStopSequences {
+		errs = append(errs, fmt.Sprintf("at most %d stop sequences are allowed", maxStopSequences))
+	}
+	for i, s := range p.Stop {
+		if s == "" {
+			errs = append(errs, fmt.Sprintf("stop sequence %d is empty", i+1))
+		}
+	}
+	switch p.ResponseFormat {
+	case "", ResponseFormatText, ResponseFormatJSON:
+	default:
+		errs = append(errs, fmt.Sprintf("unsupported response format: %s", p.ResponseFormat))
+	}
+	if p.MaxInputTokens != nil && *p.MaxInputTokens <= 0 {
+		errs = append(errs, "maxInputTokens must be positive")
+	}
+	switch p.BudgetAction {
+	case "", BudgetActionReject, BudgetActionWarn:
+	default:
+		errs = append(errs, fmt.Sprintf("unsupported budget action: %s", p.BudgetAction))
+	}
+	return errs
+}
+
+// IsEmpty reports whether no parameter is set
+func (p *ModelParameters) IsEmpty() bool {
+	return p == nil || (p.Model == "" && p.Temperature == nil && p.TopP == nil && p.MaxTokens == nil &&
+		len(p.Stop) == 0 && p.ResponseFormat == "" && p.MaxInputTokens == nil && p.BudgetAction == "")
+}
@@ -0,0 +1,96 @@
+package entities
+
+import (
+	"strings"
+	"time"
+)
+
+// RoleReviewer is the role, carried in the X-Roles header, allowed to approve
+// and reject templates unless other reviewer roles are configured
+const RoleReviewer = "prompt-reviewer"
+
+// TemplateReviewAction moves a template from one status to another
+type TemplateReviewAction string
+
+const (
+	ReviewSubmit   TemplateReviewAction = "submit"   // draft to review
+	ReviewApprove  TemplateReviewAction = "approve"  // review to active, reviewers only
+	ReviewReject   TemplateReviewAction = "reject"   // review to draft, reviewers only, with a comment
+	ReviewActivate TemplateReviewAction = "activate" // draft or archived to active, approved versions only
+	ReviewArchive  TemplateReviewAction = "archive"  // any status to archived
+	ReviewReopen   TemplateReviewAction = "reopen"   // review, active or archived back to draft
+	// ReviewRevise is recorded when a new version sends a template under
+	// review or active back to draft; it cannot be requested
+	ReviewRevise TemplateReviewAction = "revise"
+	// ReviewRestore is recorded when a rollback restores an approved version
+	// as a new one, which carries the approval over; it cannot be requested
+	ReviewRestore TemplateReviewAction = "restore"
+)
+
+// TemplateReview is an entry in the review history of a template. Every
+// status change is recorded, with the version it applied to.
+type TemplateReview struct {
+	ID         string               `json:"id"`
+	TemplateID string               `json:"templateId"`
+	Version    string               `json:"version"`
+	Action     TemplateReviewAction `json:"action"`
+	From       TemplateStatus       `json:"from"`
+	To         TemplateStatus       `json:"to"`
+	Actor      string               `json:"actor,omitempty"` // X-User-ID of the caller, if known
+	Comment    string               `json:"comment,omitempty"`
+	CreatedAt  time.Time            `json:"createdAt"`
+}
+
+// Actor is the caller of a request as identified by the gateway
+type Actor struct {
+	ID       string
+	TenantID string
+	Roles    []string
+}
+
+// ParseRoles splits a comma-separated X-Roles header
+func ParseRoles(header string) []string {
+	var roles []string
+	for _, role := range strings.Split(header, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// HasRole reports whether the actor holds any of the roles, ignoring case
+func (a Actor) HasRole(roles ...string) bool {
+	for _, held := range a.Roles {
+		for _, role := range roles {
+			if strings.EqualFold(held, role) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReviewTemplatePayload payload for moving a template through the workflow
+type ReviewTemplatePayload struct {
+	ID      string
+	Action  TemplateReviewAction
+	Comment string
+	Actor   Actor
+}
+
+// TemplateStatusAction returns the action that moves a template to status,
+// for callers that set a status rather than name an action
+func TemplateStatusAction(status TemplateStatus) (TemplateReviewAction, bool) {
+	switch status {
+	case TemplateStatusReview:
+		return ReviewSubmit, true
+	case TemplateStatusActive:
+		return ReviewActivate, true
+	case TemplateStatusArchived:
+		return ReviewArchive, true
+	case TemplateStatusDraft:
+		return ReviewReopen, true
+	}
+	return "", false
+}
@@ -1,80 +1,290 @@
 package entities
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
-// TemplateStatus represents the status of a prompt template
+// TemplateStatus represents the status of a prompt template. Templates move
+// between them through the review workflow, see TemplateReviewAction.
 type TemplateStatus string
 
 const (
 	TemplateStatusActive   TemplateStatus = "active"
 	TemplateStatusArchived TemplateStatus = "archived"
 	TemplateStatusDraft    TemplateStatus = "draft"
+	TemplateStatusReview   TemplateStatus = "review" // submitted, awaiting a reviewer
+)
+
+// IsValid reports whether the status is known
+func (s TemplateStatus) IsValid() bool {
+	switch s {
+	case TemplateStatusActive, TemplateStatusArchived, TemplateStatusDraft, TemplateStatusReview:
+		return true
+	}
+	return false
+}
+
+// TemplateSyntax selects how a template's content is rendered
+type TemplateSyntax string
+
+const (
+	// TemplateSyntaxGo renders content with text/template, e.g. {{.projectName}}
+	TemplateSyntaxGo TemplateSyntax = "go"
+	// TemplateSyntaxLegacy substitutes bare placeholders, e.g. {{projectName}}
+	TemplateSyntaxLegacy TemplateSyntax = "legacy"
+)
+
+// IsValid reports whether the syntax is supported
+func (s TemplateSyntax) IsValid() bool {
+	return s == TemplateSyntaxGo || s == TemplateSyntaxLegacy
+}
+
+// Supported variable types
+const (
+	VariableTypeString  = "string"
+	VariableTypeNumber  = "number"
+	VariableTypeBoolean = "boolean"
+	VariableTypeJSON    = "json"
 )
 
 // Variable represents a variable in a prompt template
 type Variable struct {
-	Name         string
-	Description  string
-	Type         string // string, number, boolean, json
-	Required     bool
-	DefaultValue string
+	Name         string `json:"name" yaml:"name"`
+	Description  string `json:"description" yaml:"description,omitempty"`
+	Type         string `json:"type" yaml:"type,omitempty"` // string, number, boolean, json
+	Required     bool   `json:"required" yaml:"required,omitempty"`
+	DefaultValue string `json:"defaultValue" yaml:"defaultValue,omitempty"`
+	Schema       string `json:"schema,omitempty" yaml:"schema,omitempty"`     // optional JSON Schema for json variables
+	Inferred     bool   `json:"inferred,omitempty" yaml:"inferred,omitempty"` // inferred from the content, not declared
 }
 
 // PromptTemplate represents a reusable prompt structure
 type PromptTemplate struct {
-	ID          string
-	Name        string
-	Description string
-	Version     string
-	Content     string
-	Variables   []Variable
-	Tags        []string
-	Status      TemplateStatus
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID             string            `json:"id"`
+	TenantID       string            `json:"tenantId,omitempty"` // owning tenant, empty for global templates
+	Name           string            `json:"name"`
+	Locale         string            `json:"locale"`
+	Description    string            `json:"description"`
+	Version        string            `json:"version"`
+	Content        string            `json:"content"`
+	Syntax         TemplateSyntax    `json:"syntax"`
+	Kind           TemplateKind      `json:"kind"`
+	Messages       []TemplateMessage `json:"messages,omitempty"` // decoded Content of chat templates
+	Parameters     *ModelParameters  `json:"parameters,omitempty"`
+	Variables      []Variable        `json:"variables"`
+	Tags           []string          `json:"tags"`
+	Lint           []LintIssue       `json:"lint,omitempty"`           // warnings found when saving, not stored
+	Locales        []string          `json:"locales,omitempty"`        // locales the name has variants in, set by list
+	MissingLocales []string          `json:"missingLocales,omitempty"` // supported locales without a variant, set by list
+	Status         TemplateStatus    `json:"status"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	UpdatedAt      time.Time         `json:"updatedAt"`
+}
+
+// PromptTemplateVersion is an immutable snapshot of a template's content,
+// variables and model parameters. A new version is recorded every time any of
+// them changes.
+type PromptTemplateVersion struct {
+	ID         string            `json:"id"`
+	TemplateID string            `json:"templateId"`
+	Version    string            `json:"version"`
+	Number     int               `json:"number"`
+	Content    string            `json:"content"`
+	Syntax     TemplateSyntax    `json:"syntax"`
+	Kind       TemplateKind      `json:"kind"`
+	Messages   []TemplateMessage `json:"messages,omitempty"`
+	Parameters *ModelParameters  `json:"parameters,omitempty"`
+	Variables  []Variable        `json:"variables"`
+	Tags       []string          `json:"tags,omitempty"`   // tags when the version was recorded; nil for older versions
+	Source     *TemplateSource   `json:"source,omitempty"` // set for versions synced from a git working tree
+	CreatedAt  time.Time         `json:"createdAt"`
+}
+
+// TemplateSource is the file a version was synced from
+type TemplateSource struct {
+	Path   string `json:"path"`   // relative to the root of the repository
+	Commit string `json:"commit"` // HEAD when synced, suffixed "-dirty" if the file differed from it
 }
 
-// PromptExperiment represents an A/B test for prompts
-type PromptExperiment struct {
-	ID               string
-	Name             string
-	PromptTemplateID string
-	ModelID          string
-	Config           map[string]string
-	Status           string // active, completed
-	CreatedAt        time.Time
+const (
+	// LabelProduction is the label rendered when a request names none
+	LabelProduction = "production"
+	// LabelLatest is reserved and always resolves to the current version
+	LabelLatest = "latest"
+)
+
+// labelPattern restricts label names, e.g. "production", "staging", "canary"
+var labelPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,49}$`)
+
+// TemplateLabel points a named environment at a version of a template
+type TemplateLabel struct {
+	TemplateID string    `json:"templateId"`
+	Label      string    `json:"label"`
+	Version    string    `json:"version"`
+	UpdatedAt  time.Time `json:"updatedAt"`
 }
 
-// RenderedPrompt represents the result of filling a template
+// ValidLabel reports whether name can be used as a label. Names that parse
+// as versions are rejected so "name@v3" and "name@staging" stay unambiguous.
+func ValidLabel(name string) bool {
+	if _, isVersion := ParseVersion(name); isVersion {
+		return false
+	}
+	return labelPattern.MatchString(name) && name != LabelLatest
+}
+
+// RenderedPrompt represents the result of filling a template. For chat
+// templates Messages holds the rendered messages and Content their JSON form.
 type RenderedPrompt struct {
-	Content   string
-	Variables map[string]string
+	Content    string            `json:"content,omitempty"`
+	Messages   []RenderedMessage `json:"messages,omitempty"`
+	Parameters *ModelParameters  `json:"parameters,omitempty"`
+	Tokens     *TokenEstimate    `json:"tokens,omitempty"`
+	Warnings   []string          `json:"warnings,omitempty"`
+	Variables  map[string]string `json:"variables"`
+	Version    string            `json:"version"`
+	Locale     string            `json:"locale"`          // locale of the variant that was rendered
+	Label      string            `json:"label,omitempty"` // label the version was resolved through, if any
+}
+
+// TokenEstimate is the estimated size of a rendered prompt
+type TokenEstimate struct {
+	Count     int    `json:"count"`
+	Tokenizer string `json:"tokenizer"`
+	Budget    *int   `json:"budget,omitempty"`
+}
+
+// RenderOptions tune a render beyond the template reference and variables
+type RenderOptions struct {
+	CountTokens bool             // estimate tokens even when no budget is declared
+	Parameters  *ModelParameters // override the parameters of the template
+	Locale      string           // preferred locale; its fallback chain is tried in order
 }
 
 // CreateTemplatePayload payload for creating a template
 type CreateTemplatePayload struct {
 	Name        string
+	Locale      string // variant of Name; empty for the default locale
 	Description string
 	Content     string
+	Syntax      TemplateSyntax
+	Kind        TemplateKind
+	Messages    []TemplateMessage // for chat templates, instead of Content
+	Parameters  *ModelParameters
 	Variables   []Variable
 	Tags        []string
+	Includes    []TemplateInclude // partials referenced by Content, filled in by the usecase
+	Source      *TemplateSource   // recorded on the first version
 }
 
 // UpdateTemplatePayload payload for updating a template
 type UpdateTemplatePayload struct {
-	ID        string
-	Content   string
-	Variables []Variable
-	Status    TemplateStatus
-	Tags      []string
+	ID          string
+	Content     string
+	Syntax      TemplateSyntax
+	Kind        TemplateKind
+	Messages    []TemplateMessage // for chat templates, instead of Content
+	Parameters  *ModelParameters  // replaces the current parameters when not nil; empty clears them
+	Variables   []Variable
+	Status      TemplateStatus // moves the template through the review workflow, see TemplateStatusAction
+	Tags        []string
+	Description *string           // replaces the description when not nil
+	Source      *TemplateSource   // recorded on the version the update creates, if any
+	Includes    []TemplateInclude // replaces the recorded partials when not nil
+	Actor       Actor             // recorded with a status change
 }
 
+// TemplateInclude is a reference from a template to a partial it includes
+type TemplateInclude struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"` // pinned revision, empty to follow the current one
+}
+
+// Ref returns the include reference as written in templates, e.g. "lang-vi@v2"
+func (i TemplateInclude) Ref() string {
+	if i.Version == "" {
+		return i.Name
+	}
+	return i.Name + "@" + i.Version
+}
+
+// TemplateDependent is a template that includes a partial, either directly
+// or through other partials.
+type TemplateDependent struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Locale   string `json:"locale"`
+	Version  string `json:"version"`
+	Includes string `json:"includes"` // the reference it includes, e.g. "lang-vi@v2"
+	Pinned   bool   `json:"pinned"`   // pinned dependents are not affected by new versions
+	Depth    int    `json:"depth"`    // 1 for direct includes
+}
+
+// TagMatchMode selects how TemplateFilter.Tags are matched
+type TagMatchMode string
+
+const (
+	// TagMatchAll matches templates carrying every requested tag
+	TagMatchAll TagMatchMode = "all"
+	// TagMatchAny matches templates carrying at least one requested tag
+	TagMatchAny TagMatchMode = "any"
+)
+
 // TemplateFilter filter for listing templates
 type TemplateFilter struct {
+	Query    string // full-text query over name, description and content
 	Status   TemplateStatus
 	Tags     []string
+	TagMode  TagMatchMode
+	Locale   string // only variants in this locale
 	Page     int32
 	PageSize int32
 }
+
+// TemplateSearchHit is a template matched by a full-text query
+type TemplateSearchHit struct {
+	Template *PromptTemplate `json:"template"`
+	Rank     float64         `json:"rank"`
+	Snippet  string          `json:"snippet"` // content excerpt with matches wrapped in <mark> tags
+}
+
+// TagCount is a tag and the number of templates carrying it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// FormatVersion returns the display label of a version number, e.g. "v3".
+func FormatVersion(number int) string {
+	return fmt.Sprintf("v%d", number)
+}
+
+// ParseVersion accepts "v3" or "3" and returns the version number.
+func ParseVersion(version string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(version), "v"))
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
+
+// ParseInclude splits an include reference such as "lang-vi" or "lang-vi@v2"
+// and normalizes the pinned version.
+func ParseInclude(ref string) (TemplateInclude, bool) {
+	name, version, pinned := strings.Cut(strings.TrimSpace(ref), "@")
+	if name == "" {
+		return TemplateInclude{}, false
+	}
+	if !pinned {
+		return TemplateInclude{Name: name}, true
+	}
+	number, ok := ParseVersion(version)
+	if !ok {
+		return TemplateInclude{}, false
+	}
+	return TemplateInclude{Name: name, Version: FormatVersion(number)}, true
+}
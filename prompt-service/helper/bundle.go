@@ -0,0 +1,191 @@
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"gopkg.in/yaml.v3"
+)
+
+// Prompt bundle file formats
+const (
+	BundleFormatYAML     = "yaml"
+	BundleFormatMarkdown = "md"
+)
+
+// frontmatterDelimiter opens and closes the YAML frontmatter of a Markdown
+// prompt file
+const frontmatterDelimiter = "---"
+
+// ReadPromptBundle reads every YAML (.yaml, .yml) and Markdown (.md) prompt
+// file under dir, in path order. Other files are ignored.
+func ReadPromptBundle(dir string) ([]*entities.PromptFile, error) {
+	var files []*entities.PromptFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || BundleFormat(path) == "" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		file, err := ParsePromptFile(filepath.ToSlash(rel), data)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// BundleFormat returns the format of a prompt file from its extension, or ""
+// for files that are not prompts
+func BundleFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return BundleFormatYAML
+	case ".md":
+		return BundleFormatMarkdown
+	}
+	return ""
+}
+
+// ParsePromptFile decodes a prompt file. Markdown files keep the fields in
+// optional YAML frontmatter and the content in the body. Without a name the
+// file name is used, so plain Markdown files are prompts too.
+func ParsePromptFile(path string, data []byte) (*entities.PromptFile, error) {
+	file := &entities.PromptFile{}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	switch BundleFormat(path) {
+	case BundleFormatYAML:
+		if err := decodeStrict(text, file); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	case BundleFormatMarkdown:
+		body := text
+		if rest, ok := strings.CutPrefix(text, frontmatterDelimiter+"\n"); ok {
+			frontmatter, after, found := strings.Cut(rest, "\n"+frontmatterDelimiter+"\n")
+			if !found {
+				frontmatter, found = strings.CutSuffix(rest, "\n"+frontmatterDelimiter)
+				after = ""
+			}
+			if !found {
+				return nil, fmt.Errorf("%s: frontmatter is not closed", path)
+			}
+			if err := decodeStrict(frontmatter, file); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			body = after
+		}
+		if file.Content != "" {
+			return nil, fmt.Errorf("%s: content belongs in the body, not the frontmatter", path)
+		}
+		// Files end with a newline that is not part of the content
+		file.Content = strings.TrimSuffix(body, "\n")
+	default:
+		return nil, fmt.Errorf("%s: unsupported prompt file type", path)
+	}
+
+	file.Path = path
+	if file.Name == "" {
+		file.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if file.Content != "" && len(file.Messages) > 0 {
+		return nil, fmt.Errorf("%s: a prompt has either content or messages", path)
+	}
+	return file, nil
+}
+
+// EncodePromptFile encodes a prompt file in the given format. Chat prompts
+// have no body, so they are always written as YAML.
+func EncodePromptFile(file *entities.PromptFile, format string) ([]byte, error) {
+	if format == BundleFormatYAML || len(file.Messages) > 0 {
+		return marshalYAML(file)
+	}
+
+	fields := *file
+	fields.Content = ""
+	frontmatter, err := marshalYAML(&fields)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	out.WriteString(frontmatterDelimiter + "\n")
+	out.Write(frontmatter)
+	out.WriteString(frontmatterDelimiter + "\n")
+	out.WriteString(file.Content)
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
+
+// PromptFileName names the file a prompt is exported to: its name, with the
+// locale for variants other than defaultLocale ("ba-discovery-system.en.md")
+func PromptFileName(file *entities.PromptFile, format string, defaultLocale string) string {
+	if len(file.Messages) > 0 {
+		format = BundleFormatYAML
+	}
+	name := file.Name
+	if file.Locale != "" && file.Locale != defaultLocale {
+		name += "." + file.Locale
+	}
+	return name + "." + format
+}
+
+// WritePromptBundle writes each file to dir under its Path, creating the
+// directory as needed
+func WritePromptBundle(dir string, files []*entities.PromptFile, format string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := EncodePromptFile(file, format)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.Path, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(file.Path)), data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func marshalYAML(v interface{}) ([]byte, error) {
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// decodeStrict decodes YAML, rejecting unknown fields so typos such as
+// "variable:" are reported instead of silently dropped
+func decodeStrict(text string, out *entities.PromptFile) error {
+	decoder := yaml.NewDecoder(strings.NewReader(text))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
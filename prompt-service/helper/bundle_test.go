@@ -0,0 +1,163 @@
+package helper
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blcvn/backend/services/prompt-service/entities"
+)
+
+func TestPromptFileRoundTrip(t *testing.T) {
+	textPrompt := &entities.PromptFile{
+		Name:        "ba-discovery-system",
+		Locale:      "en",
+		Description: "System prompt for discovery",
+		Kind:        entities.TemplateKindText,
+		Syntax:      entities.TemplateSyntaxGo,
+		Tags:        []string{"ba", "discovery"},
+		Variables: []entities.Variable{
+			{Name: "project", Type: "string", Required: true},
+			{Name: "depth", Type: "number", DefaultValue: "2"},
+		},
+		Parameters: &entities.ModelParameters{},
+		Content:    "You are helping with {{.project}}.\n\n---\n\nAsk {{.depth}} questions.",
+	}
+	chatPrompt := &entities.PromptFile{
+		Name: "ba-discovery-chat",
+		Kind: entities.TemplateKindChat,
+		Messages: []entities.TemplateMessage{
+			{Role: "system", Content: "You are a business analyst."},
+			{Role: "user", Content: "Hi", Example: true},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		file   *entities.PromptFile
+		format string
+		path   string
+	}{
+		{"text as yaml", textPrompt, BundleFormatYAML, "prompts/ba-discovery-system.en.yaml"},
+		{"text as markdown", textPrompt, BundleFormatMarkdown, "prompts/ba-discovery-system.en.md"},
+		{"chat as yaml", chatPrompt, BundleFormatYAML, "ba-discovery-chat.yaml"},
+		{"chat always as yaml", chatPrompt, BundleFormatMarkdown, "ba-discovery-chat.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodePromptFile(tt.file, tt.format)
+			if err != nil {
+				t.Fatalf("EncodePromptFile() error = %v", err)
+			}
+			got, err := ParsePromptFile(tt.path, data)
+			if err != nil {
+				t.Fatalf("ParsePromptFile() error = %v\n%s", err, data)
+			}
+			want := *tt.file
+			want.Path = tt.path
+			if !reflect.DeepEqual(got, &want) {
+				t.Errorf("round trip = %+v, want %+v\n%s", got, &want, data)
+			}
+		})
+	}
+}
+
+func TestParsePromptFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		data    string
+		want    *entities.PromptFile
+		wantErr string
+	}{
+		{
+			name: "plain markdown takes the file name",
+			path: "greeting.md",
+			data: "Hello {{.name}}\n",
+			want: &entities.PromptFile{Path: "greeting.md", Name: "greeting", Content: "Hello {{.name}}"},
+		},
+		{
+			name: "crlf line endings",
+			path: "greeting.md",
+			data: "---\r\nname: hello\r\n---\r\nHi\r\n",
+			want: &entities.PromptFile{Path: "greeting.md", Name: "hello", Content: "Hi"},
+		},
+		{
+			name: "frontmatter without a body",
+			path: "empty.md",
+			data: "---\nname: empty\n---",
+			want: &entities.PromptFile{Path: "empty.md", Name: "empty"},
+		},
+		{
+			name: "yaml",
+			path: "a/b.yml",
+			data: "name: b\nlocale: en\ncontent: Hi\n",
+			want: &entities.PromptFile{Path: "a/b.yml", Name: "b", Locale: "en", Content: "Hi"},
+		},
+		{name: "unknown field", path: "a.yaml", data: "name: a\nvariable: []\n", wantErr: "variable"},
+		{name: "unclosed frontmatter", path: "a.md", data: "---\nname: a\nHi\n", wantErr: "not closed"},
+		{name: "content in frontmatter", path: "a.md", data: "---\ncontent: Hi\n---\n", wantErr: "body"},
+		{
+			name:    "content and messages",
+			path:    "a.yaml",
+			data:    "content: Hi\nmessages:\n  - role: user\n    content: Hi\n",
+			wantErr: "either content or messages",
+		},
+		{name: "unsupported type", path: "a.txt", data: "Hi", wantErr: "unsupported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePromptFile(tt.path, []byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParsePromptFile() error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePromptFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePromptFile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptFileName(t *testing.T) {
+	chat := []entities.TemplateMessage{{Role: "user", Content: "Hi"}}
+	tests := []struct {
+		file   entities.PromptFile
+		format string
+		want   string
+	}{
+		{entities.PromptFile{Name: "greeting", Locale: "vi"}, BundleFormatMarkdown, "greeting.md"},
+		{entities.PromptFile{Name: "greeting"}, BundleFormatYAML, "greeting.yaml"},
+		{entities.PromptFile{Name: "greeting", Locale: "en"}, BundleFormatMarkdown, "greeting.en.md"},
+		{entities.PromptFile{Name: "chat", Locale: "en", Messages: chat}, BundleFormatMarkdown, "chat.en.yaml"},
+	}
+	for _, tt := range tests {
+		if got := PromptFileName(&tt.file, tt.format, "vi"); got != tt.want {
+			t.Errorf("PromptFileName(%s, %s) = %s, want %s", tt.file.Name, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestPromptBundleRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	files := []*entities.PromptFile{
+		{Path: "b.md", Name: "b", Content: "Second"},
+		{Path: "a.en.md", Name: "a", Locale: "en", Content: "First"},
+	}
+	if err := WritePromptBundle(dir, files, BundleFormatMarkdown); err != nil {
+		t.Fatalf("WritePromptBundle() error = %v", err)
+	}
+	got, err := ReadPromptBundle(dir)
+	if err != nil {
+		t.Fatalf("ReadPromptBundle() error = %v", err)
+	}
+	want := []*entities.PromptFile{files[1], files[0]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadPromptBundle() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,182 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines shown around each change
+	diffContext = 3
+	// maxDiffCells bounds the LCS table of a diff, 16MB of int32s
+	maxDiffCells = 1 << 22
+)
+
+// DiffOp is the kind of a line in a diff
+type DiffOp byte
+
+const (
+	DiffEqual  DiffOp = ' '
+	DiffDelete DiffOp = '-'
+	DiffInsert DiffOp = '+'
+)
+
+// DiffLine is one line of a line diff
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// DiffLines computes a shortest line diff turning from into to, from the
+// longest common subsequence of their lines. When the changed regions are
+// too large to compare line by line within maxDiffCells, the region is shown
+// as deleted and inserted whole instead.
+func DiffLines(from string, to string) []DiffLine {
+	a, b := splitLines(from), splitLines(to)
+
+	// The common prefix and suffix are trimmed so the table only covers the
+	// changed region, which is usually small for prompt edits
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	lines := make([]DiffLine, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		lines = append(lines, DiffLine{DiffEqual, line})
+	}
+	if (len(ma)+1)*(len(mb)+1) > maxDiffCells {
+		for _, line := range ma {
+			lines = append(lines, DiffLine{DiffDelete, line})
+		}
+		for _, line := range mb {
+			lines = append(lines, DiffLine{DiffInsert, line})
+		}
+	} else {
+		lines = append(lines, diffRegion(ma, mb)...)
+	}
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{DiffEqual, line})
+	}
+	return lines
+}
+
+// diffRegion diffs the changed region of two texts through an LCS table
+func diffRegion(ma []string, mb []string) []DiffLine {
+	// lcs[i][j] is the length of the LCS of ma[i:] and mb[j:]
+	lcs := make([][]int32, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]DiffLine, 0, len(ma)+len(mb))
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			lines = append(lines, DiffLine{DiffEqual, ma[i]})
+			i, j = i+1, j+1
+		case j < len(mb) && (i == len(ma) || lcs[i][j+1] > lcs[i+1][j]):
+			lines = append(lines, DiffLine{DiffInsert, mb[j]})
+			j++
+		default:
+			lines = append(lines, DiffLine{DiffDelete, ma[i]})
+			i++
+		}
+	}
+	return lines
+}
+
+// UnifiedDiff formats the difference between two texts in unified diff
+// format with the given file labels. It returns "" when they are equal.
+func UnifiedDiff(from string, to string, fromLabel string, toLabel string) string {
+	lines := DiffLines(from, to)
+
+	var out strings.Builder
+	for start := 0; start < len(lines); {
+		// Find the next change and the extent of its hunk, which absorbs
+		// changes separated by less than twice the context
+		first := start
+		for first < len(lines) && lines[first].Op == DiffEqual {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		end := first
+		for gap := 0; end < len(lines) && gap <= 2*diffContext; end++ {
+			if lines[end].Op == DiffEqual {
+				gap++
+			} else {
+				gap = 0
+			}
+		}
+		end = min(len(lines), end)
+		for end > first && lines[end-1].Op == DiffEqual {
+			end--
+		}
+		hunkStart := max(start, first-diffContext)
+		hunkEnd := min(len(lines), end+diffContext)
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromLabel, toLabel)
+		}
+		fromLine, toLine := lineNumbers(lines[:hunkStart])
+		fromCount, toCount := lineNumbers(lines[hunkStart:hunkEnd])
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount))
+		for _, line := range lines[hunkStart:hunkEnd] {
+			out.WriteByte(byte(line.Op))
+			out.WriteString(line.Text)
+			out.WriteByte('\n')
+		}
+		start = hunkEnd
+	}
+	return out.String()
+}
+
+// lineNumbers counts the lines of each side in part of a diff
+func lineNumbers(lines []DiffLine) (int, int) {
+	from, to := 0, 0
+	for _, line := range lines {
+		if line.Op != DiffInsert {
+			from++
+		}
+		if line.Op != DiffDelete {
+			to++
+		}
+	}
+	return from, to
+}
+
+// hunkRange formats the start and length of one side of a hunk; empty
+// ranges point at the line before them, as in GNU diff
+func hunkRange(before int, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
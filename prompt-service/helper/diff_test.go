@@ -0,0 +1,124 @@
+package helper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []DiffLine
+	}{
+		{"both empty", "", "", []DiffLine{}},
+		{"equal", "a\nb\n", "a\nb", []DiffLine{{DiffEqual, "a"}, {DiffEqual, "b"}}},
+		{"insert", "", "a\n", []DiffLine{{DiffInsert, "a"}}},
+		{"delete", "a\nb\nc", "a\nc", []DiffLine{{DiffEqual, "a"}, {DiffDelete, "b"}, {DiffEqual, "c"}}},
+		{
+			"replace",
+			"a\nb\nc",
+			"a\nB\nc",
+			[]DiffLine{{DiffEqual, "a"}, {DiffDelete, "b"}, {DiffInsert, "B"}, {DiffEqual, "c"}},
+		},
+		{
+			"keeps the common subsequence",
+			"x\na\ny\nb",
+			"a\nz\nb",
+			[]DiffLine{{DiffDelete, "x"}, {DiffEqual, "a"}, {DiffDelete, "y"}, {DiffInsert, "z"}, {DiffEqual, "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffLines(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesLargeRegion(t *testing.T) {
+	// A changed region too large for the LCS table is replaced whole
+	n := 2100
+	var from, to strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&from, "old %d\n", i)
+		fmt.Fprintf(&to, "new %d\n", i)
+	}
+	lines := DiffLines("head\n"+from.String()+"tail", "head\n"+to.String()+"tail")
+	if len(lines) != 2*n+2 {
+		t.Fatalf("len(DiffLines()) = %d, want %d", len(lines), 2*n+2)
+	}
+	if lines[0] != (DiffLine{DiffEqual, "head"}) || lines[len(lines)-1] != (DiffLine{DiffEqual, "tail"}) {
+		t.Errorf("common prefix and suffix were not kept: %v ... %v", lines[0], lines[len(lines)-1])
+	}
+	for i, line := range lines[1 : len(lines)-1] {
+		want := DiffDelete
+		if i >= n {
+			want = DiffInsert
+		}
+		if line.Op != want {
+			t.Fatalf("line %d is %c, want %c", i+1, line.Op, want)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	numbered := func(from, to int, replace map[int]string) string {
+		var b strings.Builder
+		for i := from; i <= to; i++ {
+			if text, ok := replace[i]; ok {
+				b.WriteString(text + "\n")
+				continue
+			}
+			fmt.Fprintf(&b, "%d\n", i)
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{
+			"single change",
+			"a\nb\nc\n",
+			"a\nB\nc\n",
+			"--- v1\n+++ v2\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{"from empty", "", "x\n", "--- v1\n+++ v2\n@@ -0,0 +1 @@\n+x\n"},
+		{"to empty", "x\n", "", "--- v1\n+++ v2\n@@ -1 +0,0 @@\n-x\n"},
+		{
+			"context is limited to three lines",
+			numbered(1, 10, nil),
+			numbered(1, 10, map[int]string{5: "five"}),
+			"--- v1\n+++ v2\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			"nearby changes share a hunk",
+			numbered(1, 10, nil),
+			numbered(1, 10, map[int]string{2: "two", 8: "eight"}),
+			"--- v1\n+++ v2\n@@ -1,10 +1,10 @@\n 1\n-2\n+two\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n 9\n 10\n",
+		},
+		{
+			"distant changes get their own hunks",
+			numbered(1, 20, nil),
+			numbered(1, 20, map[int]string{2: "two", 18: "eighteen"}),
+			"--- v1\n+++ v2\n@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n" +
+				"@@ -15,6 +15,6 @@\n 15\n 16\n 17\n-18\n+eighteen\n 19\n 20\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff(tt.from, tt.to, "v1", "v2"); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
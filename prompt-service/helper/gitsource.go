@@ -0,0 +1,132 @@
+package helper
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/blcvn/backend/services/prompt-service/entities"
+)
+
+// dirtySuffix marks the commit of a file that differs from it
+const dirtySuffix = "-dirty"
+
+// GitSource reads a prompt bundle from a directory in a git working tree,
+// such as a checked-out prompts repository, and records where each file
+// came from. Outside a git repository files are read without a source.
+type GitSource struct {
+	dir  string
+	root string // top level of the working tree, "" outside git
+}
+
+// SourceSnapshot is the state of a GitSource at one point in time
+type SourceSnapshot struct {
+	Files       []*entities.PromptFile
+	Commit      string // HEAD, "" outside git
+	Fingerprint string // changes whenever HEAD or a prompt file changes
+}
+
+// NewGitSource opens dir, which must exist
+func NewGitSource(dir string) (*GitSource, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return nil, err
+	}
+	// git reports the top level with symlinks resolved
+	if abs, err = filepath.EvalSymlinks(abs); err != nil {
+		return nil, err
+	}
+	source := &GitSource{dir: abs}
+	if out, err := git(abs, "rev-parse", "--show-toplevel"); err == nil {
+		source.root = strings.TrimSpace(out)
+	}
+	return source, nil
+}
+
+// Snapshot reads the bundle with the current commit. Each file's source
+// commit carries a "-dirty" suffix when the file has uncommitted changes, so
+// a version is never attributed to a commit that does not contain it.
+func (s *GitSource) Snapshot() (*SourceSnapshot, error) {
+	files, err := ReadPromptBundle(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &SourceSnapshot{Files: files}
+
+	var dirty map[string]bool
+	if s.root != "" {
+		out, err := git(s.root, "rev-parse", "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Commit = strings.TrimSpace(out)
+		if dirty, err = s.dirtyPaths(); err != nil {
+			return nil, err
+		}
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n", snapshot.Commit)
+	for _, file := range files {
+		path := filepath.Join(s.dir, filepath.FromSlash(file.Path))
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(hash, "%s %d %d\n", file.Path, info.Size(), info.ModTime().UnixNano())
+
+		if s.root != "" {
+			rel, _ := filepath.Rel(s.root, path)
+			rel = filepath.ToSlash(rel)
+			commit := snapshot.Commit
+			if dirty[rel] {
+				commit += dirtySuffix
+			}
+			file.Source = &entities.TemplateSource{Path: rel, Commit: commit}
+		}
+	}
+	snapshot.Fingerprint = hex.EncodeToString(hash.Sum(nil))
+	return snapshot, nil
+}
+
+// dirtyPaths lists the files under the directory that are modified or
+// untracked, relative to the root of the working tree
+func (s *GitSource) dirtyPaths() (map[string]bool, error) {
+	out, err := git(s.root, "status", "--porcelain=v1", "-z", "--untracked-files=all", "--", s.dir)
+	if err != nil {
+		return nil, err
+	}
+	dirty := make(map[string]bool)
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		dirty[entry[3:]] = true
+		// Renames and copies are followed by their original path
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+		}
+	}
+	return dirty, nil
+}
+
+// git runs a git command in dir and returns its output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
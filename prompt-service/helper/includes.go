@@ -0,0 +1,149 @@
+package helper
+
+import (
+	"fmt"
+	"text/template/parse"
+
+	"github.com/blcvn/backend/services/prompt-service/entities"
+)
+
+// MaxIncludeDepth bounds how deeply partials can include other partials
+const MaxIncludeDepth = 5
+
+// Partial is template content that can be included by reference, e.g.
+// {{include "lang-vi"}} in Go syntax or {{> lang-vi@v2}} in legacy syntax.
+type Partial struct {
+	Content string
+	Syntax  entities.TemplateSyntax
+}
+
+// ExtractIncludes returns the distinct partial references in content, in the
+// order they first appear. Go-syntax includes must name the partial with a
+// string literal so dependencies are known before rendering.
+func (e *TemplateEngine) ExtractIncludes(content string, syntax entities.TemplateSyntax) ([]string, error) {
+	var refs []string
+	seen := make(map[string]bool)
+	add := func(ref string) {
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	if syntax == entities.TemplateSyntaxLegacy {
+		for _, match := range legacyInclude.FindAllStringSubmatch(content, -1) {
+			add(match[1])
+		}
+		return refs, nil
+	}
+
+	tmpl, err := e.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil || t.Tree.Root == nil {
+			continue
+		}
+		if err := walkIncludes(t.Tree, t.Tree.Root, add); err != nil {
+			return nil, err
+		}
+	}
+	return refs, nil
+}
+
+// renderPartial renders an included partial with the variables of the
+// including template.
+func (e *TemplateEngine) renderPartial(ref string, variables map[string]interface{}, partials map[string]*Partial, depth int, budget *renderBudget) (string, error) {
+	if depth >= MaxIncludeDepth {
+		return "", fmt.Errorf("include %q: partials are nested deeper than %d levels", ref, MaxIncludeDepth)
+	}
+	partial, ok := partials[ref]
+	if !ok {
+		return "", fmt.Errorf("include %q: partial not found", ref)
+	}
+	if err := budget.spend(1); err != nil {
+		return "", fmt.Errorf("include %q: %w", ref, err)
+	}
+	return e.renderSyntax(partial.Content, partial.Syntax, variables, partials, depth+1, budget)
+}
+
+// includeUnresolved backs the include function when no partials were loaded
+func includeUnresolved(ref string) (string, error) {
+	return "", fmt.Errorf("include %q: partial not found", ref)
+}
+
+func walkIncludes(tree *parse.Tree, node parse.Node, add func(string)) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := walkIncludes(tree, child, add); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return walkIncludePipe(tree, n.Pipe, add)
+	case *parse.TemplateNode:
+		return walkIncludePipe(tree, n.Pipe, add)
+	case *parse.IfNode:
+		return walkIncludeBranch(tree, &n.BranchNode, add)
+	case *parse.RangeNode:
+		return walkIncludeBranch(tree, &n.BranchNode, add)
+	case *parse.WithNode:
+		return walkIncludeBranch(tree, &n.BranchNode, add)
+	}
+	return nil
+}
+
+func walkIncludeBranch(tree *parse.Tree, n *parse.BranchNode, add func(string)) error {
+	if err := walkIncludePipe(tree, n.Pipe, add); err != nil {
+		return err
+	}
+	if err := walkIncludes(tree, n.List, add); err != nil {
+		return err
+	}
+	return walkIncludes(tree, n.ElseList, add)
+}
+
+func walkIncludePipe(tree *parse.Tree, pipe *parse.PipeNode, add func(string)) error {
+	if pipe == nil {
+		return nil
+	}
+	for _, cmd := range pipe.Cmds {
+		for i, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.IdentifierNode:
+				if a.Ident != "include" {
+					continue
+				}
+				ref, ok := includeArg(cmd, i)
+				if !ok {
+					location, _ := tree.ErrorContext(cmd)
+					return fmt.Errorf("%s: include takes a single quoted template name", location)
+				}
+				add(ref)
+			case *parse.PipeNode:
+				if err := walkIncludePipe(tree, a, add); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// includeArg returns the literal argument of an include call, which must be
+// the first word of its command followed by exactly one string.
+func includeArg(cmd *parse.CommandNode, i int) (string, bool) {
+	if i != 0 || len(cmd.Args) != 2 {
+		return "", false
+	}
+	s, ok := cmd.Args[1].(*parse.StringNode)
+	if !ok {
+		return "", false
+	}
+	return s.Text, true
+}
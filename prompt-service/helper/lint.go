@@ -0,0 +1,160 @@
+package helper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template/parse"
+	"unicode/utf8"
+
+	"github.com/blcvn/backend/services/prompt-service/entities"
+)
+
+// errorPosition finds "prompt:<line>:<col>" or "prompt:<line>" in template
+// parse errors
+var errorPosition = regexp.MustCompile(`prompt:(\d+)(?::(\d+))?:`)
+
+// VariableRef is a reference to a top-level variable in template content.
+// Conditional references are tested by {{if}} or {{with}}, or given a
+// fallback with default, so the template copes with the value being empty.
+// Structured references range over the value or read its fields.
+type VariableRef struct {
+	Name        string
+	Line        int
+	Column      int
+	Conditional bool
+	Structured  bool
+}
+
+// ExtractVariableRefs returns every reference to a top-level variable, in
+// order of appearance. In Go syntax these are fields of the root value such
+// as {{.projectName}} or {{$.projectName}}; fields read inside {{range}} or
+// {{with}} belong to the element and are skipped, as are {{define}} blocks.
+func (e *TemplateEngine) ExtractVariableRefs(content string, syntax entities.TemplateSyntax) ([]VariableRef, error) {
+	var refs []VariableRef
+	add := func(name string, offset int, use refUse) {
+		line, col := position(content, offset)
+		refs = append(refs, VariableRef{Name: name, Line: line, Column: col, Conditional: use.conditional, Structured: use.structured})
+	}
+
+	if syntax == entities.TemplateSyntaxLegacy {
+		for _, match := range legacyPlaceholder.FindAllStringSubmatchIndex(content, -1) {
+			add(content[match[2]:match[3]], match[0], refUse{})
+		}
+		return refs, nil
+	}
+
+	tmpl, err := e.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl.Tree != nil && tmpl.Tree.Root != nil {
+		walkVariables(tmpl.Tree.Root, true, add)
+	}
+	return refs, nil
+}
+
+// ErrorPosition extracts the line and column from a template parse error.
+// Either is 0 when the error does not carry it.
+func ErrorPosition(err error) (int, int) {
+	match := errorPosition.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0, 0
+	}
+	line, _ := strconv.Atoi(match[1])
+	col, _ := strconv.Atoi(match[2])
+	return line, col
+}
+
+// refUse describes how the pipeline being walked uses its references
+type refUse struct {
+	conditional bool
+	structured  bool
+}
+
+// walkVariables visits the nodes of a template; root reports whether dot is
+// still the value the template was executed with.
+func walkVariables(node parse.Node, root bool, add func(string, int, refUse)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkVariables(child, root, add)
+		}
+	case *parse.ActionNode:
+		walkVariablePipe(n.Pipe, root, refUse{}, add)
+	case *parse.TemplateNode:
+		walkVariablePipe(n.Pipe, root, refUse{}, add)
+	case *parse.IfNode:
+		walkVariablePipe(n.Pipe, root, refUse{conditional: true}, add)
+		walkVariables(n.List, root, add)
+		walkVariables(n.ElseList, root, add)
+	case *parse.RangeNode:
+		walkVariablePipe(n.Pipe, root, refUse{structured: true}, add)
+		walkVariables(n.List, false, add)
+		walkVariables(n.ElseList, root, add)
+	case *parse.WithNode:
+		walkVariablePipe(n.Pipe, root, refUse{conditional: true}, add)
+		walkVariables(n.List, false, add)
+		walkVariables(n.ElseList, root, add)
+	}
+}
+
+func walkVariablePipe(pipe *parse.PipeNode, root bool, use refUse, add func(string, int, refUse)) {
+	if pipe == nil {
+		return
+	}
+	use.conditional = use.conditional || pipeHasDefault(pipe)
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.FieldNode:
+				if root {
+					add(a.Ident[0], int(a.Position()), fieldUse(use, len(a.Ident)))
+				}
+			case *parse.VariableNode:
+				// $ is always the root value, whatever dot currently is
+				if a.Ident[0] == "$" && len(a.Ident) > 1 {
+					add(a.Ident[1], int(a.Position()), fieldUse(use, len(a.Ident)-1))
+				}
+			case *parse.ChainNode:
+				if p, ok := a.Node.(*parse.PipeNode); ok {
+					walkVariablePipe(p, root, use, add)
+				}
+			case *parse.PipeNode:
+				walkVariablePipe(a, root, use, add)
+			}
+		}
+	}
+}
+
+// fieldUse marks references such as .project.name as structured
+func fieldUse(use refUse, depth int) refUse {
+	if depth > 1 {
+		use.structured = true
+	}
+	return use
+}
+
+// pipeHasDefault reports whether a pipeline falls back with default, as in
+// {{.tone | default "neutral"}}
+func pipeHasDefault(pipe *parse.PipeNode) bool {
+	for _, cmd := range pipe.Cmds {
+		if len(cmd.Args) > 0 {
+			if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "default" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// position converts a byte offset into a 1-based line and column in runes
+func position(content string, offset int) (int, int) {
+	before := content[:offset]
+	line := 1 + strings.Count(before, "\n")
+	lineStart := strings.LastIndex(before, "\n") + 1
+	return line, 1 + utf8.RuneCountInString(before[lineStart:])
+}
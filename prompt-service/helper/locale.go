@@ -0,0 +1,171 @@
+package helper
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is the locale of templates created without one
+const DefaultLocale = "vi"
+
+// localeTag matches a language with an optional script and region, as in
+// "vi", "en-GB" or "zh-Hant-TW"
+var localeTag = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z][a-z]{3})?(-([A-Z]{2}|[0-9]{3}))?$`)
+
+// Locales configures the locale variants templates are expected to have and
+// how a render falls back when the requested variant does not exist.
+type Locales struct {
+	Default   string
+	Supported []string
+	fallbacks map[string]string
+}
+
+// NewLocales parses locale settings: a comma separated list of supported
+// locales ("vi,en") and of fallbacks ("en-GB=en,en=vi"). The default locale
+// is always supported.
+func NewLocales(defaultLocale string, supported string, fallbacks string) (*Locales, error) {
+	if defaultLocale == "" {
+		defaultLocale = DefaultLocale
+	}
+	def, ok := NormalizeLocale(defaultLocale)
+	if !ok {
+		return nil, fmt.Errorf("invalid default locale: %s", defaultLocale)
+	}
+
+	l := &Locales{Default: def, Supported: []string{def}, fallbacks: make(map[string]string)}
+	for _, value := range strings.Split(supported, ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		locale, ok := NormalizeLocale(value)
+		if !ok {
+			return nil, fmt.Errorf("invalid supported locale: %s", value)
+		}
+		if !l.IsSupported(locale) {
+			l.Supported = append(l.Supported, locale)
+		}
+	}
+
+	for _, pair := range strings.Split(fallbacks, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		from, to, found := strings.Cut(pair, "=")
+		source, ok1 := NormalizeLocale(strings.TrimSpace(from))
+		target, ok2 := NormalizeLocale(strings.TrimSpace(to))
+		if !found || !ok1 || !ok2 {
+			return nil, fmt.Errorf("invalid locale fallback: %s", pair)
+		}
+		l.fallbacks[source] = target
+	}
+	return l, nil
+}
+
+// DefaultLocales supports only the default locale
+func DefaultLocales() *Locales {
+	l, _ := NewLocales(DefaultLocale, "", "")
+	return l
+}
+
+// IsSupported reports whether templates are expected to have a variant in
+// locale
+func (l *Locales) IsSupported(locale string) bool {
+	for _, s := range l.Supported {
+		if s == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Chain returns the locales to try for a request in locale, most preferred
+// first. After each locale comes its configured fallback or, without one, its
+// parent tag (en-GB -> en); the default locale always ends the chain.
+func (l *Locales) Chain(locale string) []string {
+	var chain []string
+	seen := make(map[string]bool)
+	for current, ok := NormalizeLocale(locale); ok && !seen[current]; {
+		seen[current] = true
+		chain = append(chain, current)
+		if next, found := l.fallbacks[current]; found {
+			current = next
+			continue
+		}
+		i := strings.LastIndex(current, "-")
+		if i < 0 {
+			break
+		}
+		current = current[:i]
+	}
+	if !seen[l.Default] {
+		chain = append(chain, l.Default)
+	}
+	return chain
+}
+
+// Missing returns the supported locales not in have, in configured order
+func (l *Locales) Missing(have []string) []string {
+	present := make(map[string]bool, len(have))
+	for _, locale := range have {
+		present[locale] = true
+	}
+	var missing []string
+	for _, locale := range l.Supported {
+		if !present[locale] {
+			missing = append(missing, locale)
+		}
+	}
+	return missing
+}
+
+// NormalizeLocale canonicalizes the case of a locale tag, accepting
+// underscores as separators ("en_gb" -> "en-GB"), and reports whether it is
+// well formed.
+func NormalizeLocale(locale string) (string, bool) {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"), "-")
+	for i, part := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(part)
+		case len(part) == 4:
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		default:
+			parts[i] = strings.ToUpper(part)
+		}
+	}
+	normalized := strings.Join(parts, "-")
+	return normalized, localeTag.MatchString(normalized)
+}
+
+// PreferredLocale picks the first locale of an Accept-Language header such
+// as "en-GB,en;q=0.8", by quality. It returns "" when none is usable.
+func PreferredLocale(header string) string {
+	type candidate struct {
+		locale  string
+		quality float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		locale, ok := NormalizeLocale(tag)
+		if !ok {
+			continue
+		}
+		quality := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if _, err := fmt.Sscanf(q, "%g", &quality); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			candidates = append(candidates, candidate{locale, quality})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+	if len(candidates) == 0 {
+		return ""
+	}
+	return candidates[0].locale
+}
@@ -0,0 +1,137 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeLocale(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+		valid  bool
+	}{
+		{"vi", "vi", true},
+		{"EN", "en", true},
+		{"en_gb", "en-GB", true},
+		{" en-gb ", "en-GB", true},
+		{"zh-hant-tw", "zh-Hant-TW", true},
+		{"es-419", "es-419", true},
+		{"", "", false},
+		{"english", "english", false},
+		{"en-", "en-", false},
+	}
+	for _, tt := range tests {
+		got, valid := NormalizeLocale(tt.locale)
+		if got != tt.want || valid != tt.valid {
+			t.Errorf("NormalizeLocale(%q) = (%q, %v), want (%q, %v)", tt.locale, got, valid, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestNewLocales(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaultLocale string
+		supported     string
+		fallbacks     string
+		wantDefault   string
+		wantSupported []string
+		wantErr       bool
+	}{
+		{"defaults", "", "", "", DefaultLocale, []string{DefaultLocale}, false},
+		{"normalized and deduplicated", "VI", "vi, en_gb ,en,", "", "vi", []string{"vi", "en-GB", "en"}, false},
+		{"invalid default", "vietnamese", "", "", "", nil, true},
+		{"invalid supported", "vi", "en,english", "", "", nil, true},
+		{"fallback without target", "vi", "", "en", "", nil, true},
+		{"invalid fallback", "vi", "", "en=english", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLocales(tt.defaultLocale, tt.supported, tt.fallbacks)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("NewLocales() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewLocales() error = %v", err)
+			}
+			if l.Default != tt.wantDefault || !reflect.DeepEqual(l.Supported, tt.wantSupported) {
+				t.Errorf("NewLocales() = %s %v, want %s %v", l.Default, l.Supported, tt.wantDefault, tt.wantSupported)
+			}
+		})
+	}
+}
+
+func TestLocalesChain(t *testing.T) {
+	tests := []struct {
+		name      string
+		fallbacks string
+		locale    string
+		want      []string
+	}{
+		{"default locale", "", "vi", []string{"vi"}},
+		{"empty request", "", "", []string{"vi"}},
+		{"invalid request", "", "english", []string{"vi"}},
+		{"parent tags", "", "zh-Hant-TW", []string{"zh-Hant-TW", "zh-Hant", "zh", "vi"}},
+		{"request is normalized", "", "en_gb", []string{"en-GB", "en", "vi"}},
+		{"configured fallback", "fr=en", "fr-CA", []string{"fr-CA", "fr", "en", "vi"}},
+		{"fallback replaces the parent", "en-AU=en-GB", "en-AU", []string{"en-AU", "en-GB", "en", "vi"}},
+		{"fallback to the default", "en=vi", "en", []string{"en", "vi"}},
+		{"cycles stop", "en=fr,fr=en", "en", []string{"en", "fr", "vi"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLocales("vi", "", tt.fallbacks)
+			if err != nil {
+				t.Fatalf("NewLocales() error = %v", err)
+			}
+			if got := l.Chain(tt.locale); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chain(%q) = %v, want %v", tt.locale, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalesMissing(t *testing.T) {
+	l, err := NewLocales("vi", "en,ja", "")
+	if err != nil {
+		t.Fatalf("NewLocales() error = %v", err)
+	}
+	tests := []struct {
+		have []string
+		want []string
+	}{
+		{nil, []string{"vi", "en", "ja"}},
+		{[]string{"ja", "vi"}, []string{"en"}},
+		{[]string{"vi", "en", "ja", "fr"}, nil},
+	}
+	for _, tt := range tests {
+		if got := l.Missing(tt.have); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Missing(%v) = %v, want %v", tt.have, got, tt.want)
+		}
+	}
+}
+
+func TestPreferredLocale(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"en-GB,en;q=0.8", "en-GB"},
+		{"fr;q=0.5, de", "de"},
+		{"en;q=0.8, ja;q=0.8", "en"},
+		{"en_us", "en-US"},
+		{"*", ""},
+		{"en;q=0", ""},
+		{"en;q=high, vi;q=0.1", "vi"},
+	}
+	for _, tt := range tests {
+		if got := PreferredLocale(tt.header); got != tt.want {
+			t.Errorf("PreferredLocale(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
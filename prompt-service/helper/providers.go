@@ -0,0 +1,180 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blcvn/backend/services/prompt-service/entities"
+)
+
+// DefaultAnthropicMaxTokens is sent when no max tokens are configured, since
+// the Anthropic messages API requires the field.
+const DefaultAnthropicMaxTokens = 1024
+
+// OpenAIChatRequest is the body of an OpenAI chat completions request
+type OpenAIChatRequest struct {
+	Model          string                `json:"model,omitempty"`
+	Messages       []OpenAIMessage       `json:"messages"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	TopP           *float64              `json:"top_p,omitempty"`
+	MaxTokens      *int                  `json:"max_tokens,omitempty"`
+	Stop           []string              `json:"stop,omitempty"`
+	ResponseFormat *OpenAIResponseFormat `json:"response_format,omitempty"`
+}
+
+type OpenAIMessage struct {
+	Role    string `json:"role"`
+	Name    string `json:"name,omitempty"`
+	Content string `json:"content"`
+}
+
+type OpenAIResponseFormat struct {
+	Type string `json:"type"`
+}
+
+// AnthropicMessagesRequest is the body of an Anthropic messages request
+type AnthropicMessagesRequest struct {
+	Model         string             `json:"model,omitempty"`
+	System        string             `json:"system,omitempty"`
+	Messages      []AnthropicMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+}
+
+type AnthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// GeminiRequest is the body of a Gemini generateContent request. The model
+// is part of the request URL rather than the body.
+type GeminiRequest struct {
+	SystemInstruction *GeminiContent          `json:"systemInstruction,omitempty"`
+	Contents          []GeminiContent         `json:"contents"`
+	GenerationConfig  *GeminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []GeminiPart `json:"parts"`
+}
+
+type GeminiPart struct {
+	Text string `json:"text"`
+}
+
+type GeminiGenerationConfig struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"topP,omitempty"`
+	MaxOutputTokens  *int     `json:"maxOutputTokens,omitempty"`
+	StopSequences    []string `json:"stopSequences,omitempty"`
+	ResponseMimeType string   `json:"responseMimeType,omitempty"`
+}
+
+// ProviderRequest shapes rendered messages and model parameters into the
+// request body of the given provider. Few-shot examples are sent as ordinary
+// conversation turns.
+func ProviderRequest(provider entities.Provider, messages []entities.RenderedMessage, params *entities.ModelParameters) (interface{}, error) {
+	if params == nil {
+		params = &entities.ModelParameters{}
+	}
+	switch provider {
+	case entities.ProviderOpenAI:
+		return openAIRequest(messages, params), nil
+	case entities.ProviderAnthropic:
+		return anthropicRequest(messages, params)
+	case entities.ProviderGemini:
+		return geminiRequest(messages, params)
+	}
+	return nil, fmt.Errorf("unsupported provider: %s", provider)
+}
+
+func openAIRequest(messages []entities.RenderedMessage, params *entities.ModelParameters) *OpenAIChatRequest {
+	req := &OpenAIChatRequest{
+		Model:       params.Model,
+		Messages:    make([]OpenAIMessage, len(messages)),
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+		MaxTokens:   params.MaxTokens,
+		Stop:        params.Stop,
+	}
+	for i, m := range messages {
+		req.Messages[i] = OpenAIMessage{Role: m.Role, Name: m.Name, Content: m.Content}
+	}
+	if params.ResponseFormat == entities.ResponseFormatJSON {
+		req.ResponseFormat = &OpenAIResponseFormat{Type: "json_object"}
+	}
+	return req
+}
+
+func anthropicRequest(messages []entities.RenderedMessage, params *entities.ModelParameters) (*AnthropicMessagesRequest, error) {
+	if params.ResponseFormat == entities.ResponseFormatJSON {
+		return nil, fmt.Errorf("anthropic does not support the %s response format", params.ResponseFormat)
+	}
+	system, turns := splitSystem(messages)
+	if len(turns) == 0 {
+		return nil, fmt.Errorf("anthropic requests need at least one user message")
+	}
+
+	req := &AnthropicMessagesRequest{
+		Model:         params.Model,
+		System:        system,
+		Messages:      make([]AnthropicMessage, len(turns)),
+		MaxTokens:     DefaultAnthropicMaxTokens,
+		Temperature:   params.Temperature,
+		TopP:          params.TopP,
+		StopSequences: params.Stop,
+	}
+	if params.MaxTokens != nil {
+		req.MaxTokens = *params.MaxTokens
+	}
+	for i, m := range turns {
+		req.Messages[i] = AnthropicMessage{Role: m.Role, Content: m.Content}
+	}
+	return req, nil
+}
+
+func geminiRequest(messages []entities.RenderedMessage, params *entities.ModelParameters) (*GeminiRequest, error) {
+	system, turns := splitSystem(messages)
+	if len(turns) == 0 {
+		return nil, fmt.Errorf("gemini requests need at least one user message")
+	}
+
+	req := &GeminiRequest{Contents: make([]GeminiContent, len(turns))}
+	if system != "" {
+		req.SystemInstruction = &GeminiContent{Parts: []GeminiPart{{Text: system}}}
+	}
+	for i, m := range turns {
+		role := "user"
+		if m.Role == entities.RoleAssistant {
+			role = "model"
+		}
+		req.Contents[i] = GeminiContent{Role: role, Parts: []GeminiPart{{Text: m.Content}}}
+	}
+
+	if params.Temperature != nil || params.TopP != nil || params.MaxTokens != nil || len(params.Stop) > 0 || params.ResponseFormat == entities.ResponseFormatJSON {
+		req.GenerationConfig = &GeminiGenerationConfig{
+			Temperature:     params.Temperature,
+			TopP:            params.TopP,
+			MaxOutputTokens: params.MaxTokens,
+			StopSequences:   params.Stop,
+		}
+		if params.ResponseFormat == entities.ResponseFormatJSON {
+			req.GenerationConfig.ResponseMimeType = "application/json"
+		}
+	}
+	return req, nil
+}
+
+// splitSystem separates the leading system messages, which Anthropic and
+// Gemini take outside the conversation, from the conversation turns.
+func splitSystem(messages []entities.RenderedMessage) (string, []entities.RenderedMessage) {
+	var system []string
+	i := 0
+	for ; i < len(messages) && messages[i].Role == entities.RoleSystem; i++ {
+		system = append(system, messages[i].Content)
+	}
+	return strings.Join(system, "\n\n"), messages[i:]
+}
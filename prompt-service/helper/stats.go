@@ -0,0 +1,154 @@
+package helper
+
+import (
+	"math"
+)
+
+// z95 is the two-sided 95% critical value of the standard normal distribution
+const z95 = 1.959963984540054
+
+// MeanInterval returns the 95% confidence interval of a sample mean using the
+// Student t distribution.
+func MeanInterval(mean, variance float64, n int64) (float64, float64) {
+	if n < 2 {
+		return mean, mean
+	}
+	margin := StudentTQuantile(0.975, float64(n-1)) * math.Sqrt(variance/float64(n))
+	return mean - margin, mean + margin
+}
+
+// ProportionInterval returns the 95% Wilson score interval of a proportion
+func ProportionInterval(successes, n int64) (float64, float64) {
+	if n == 0 {
+		return 0, 0
+	}
+	p := float64(successes) / float64(n)
+	nf := float64(n)
+	z2 := z95 * z95
+	center := (p + z2/(2*nf)) / (1 + z2/nf)
+	margin := z95 * math.Sqrt(p*(1-p)/nf+z2/(4*nf*nf)) / (1 + z2/nf)
+	return math.Max(0, center-margin), math.Min(1, center+margin)
+}
+
+// TwoProportionZTest compares two proportions with a pooled z-test and returns
+// the z statistic and the two-sided p-value.
+func TwoProportionZTest(successesA, nA, successesB, nB int64) (float64, float64) {
+	if nA == 0 || nB == 0 {
+		return 0, 1
+	}
+	pA := float64(successesA) / float64(nA)
+	pB := float64(successesB) / float64(nB)
+	pooled := float64(successesA+successesB) / float64(nA+nB)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(nA) + 1/float64(nB)))
+	if se == 0 {
+		return 0, 1
+	}
+	z := (pB - pA) / se
+	return z, math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// WelchTTest compares two sample means without assuming equal variances and
+// returns the t statistic, the Welch-Satterthwaite degrees of freedom and the
+// two-sided p-value.
+func WelchTTest(meanA, varA float64, nA int64, meanB, varB float64, nB int64) (float64, float64, float64) {
+	if nA < 2 || nB < 2 {
+		return 0, 0, 1
+	}
+	sa := varA / float64(nA)
+	sb := varB / float64(nB)
+	if sa+sb == 0 {
+		return 0, 0, 1
+	}
+	t := (meanB - meanA) / math.Sqrt(sa+sb)
+	df := (sa + sb) * (sa + sb) / (sa*sa/float64(nA-1) + sb*sb/float64(nB-1))
+	return t, df, studentTTwoSided(t, df)
+}
+
+// studentTTwoSided returns P(|T| > |t|) for a t distribution with df degrees of freedom
+func studentTTwoSided(t, df float64) float64 {
+	return regularizedIncompleteBeta(df/(df+t*t), df/2, 0.5)
+}
+
+// StudentTQuantile returns the p-quantile (p > 0.5) of the t distribution,
+// found by bisection on the distribution function.
+func StudentTQuantile(p, df float64) float64 {
+	target := 2 * (1 - p) // two-sided tail mass
+	lo, hi := 0.0, 1.0
+	for studentTTwoSided(hi, df) > target {
+		hi *= 2
+	}
+	for i := 0; i < 100; i++ {
+		mid := (lo + hi) / 2
+		if studentTTwoSided(mid, df) > target {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// regularizedIncompleteBeta computes I_x(a, b) with the continued fraction
+// expansion from Numerical Recipes.
+func regularizedIncompleteBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(x, a, b) / a
+	}
+	return 1 - front*betaContinuedFraction(1-x, b, a)/b
+}
+
+func betaContinuedFraction(x, a, b float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+	qab, qap, qam := a+b, a+1, a-1
+	c, d := 1.0, 1-qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIterations; m++ {
+		mf := float64(m)
+		m2 := 2 * mf
+		aa := mf * (b - mf) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		aa = -(a + mf) * (qab + mf) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
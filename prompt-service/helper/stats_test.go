@@ -0,0 +1,158 @@
+package helper
+
+import (
+	"math"
+	"testing"
+)
+
+func approxEqual(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+func TestStudentTQuantile(t *testing.T) {
+	// Two-sided 95% critical values from standard t tables
+	tests := []struct {
+		df   float64
+		want float64
+	}{
+		{1, 12.7062},
+		{4, 2.7764},
+		{10, 2.2281},
+		{30, 2.0423},
+		{1e6, z95},
+	}
+	for _, tt := range tests {
+		if got := StudentTQuantile(0.975, tt.df); !approxEqual(got, tt.want, 1e-3) {
+			t.Errorf("StudentTQuantile(0.975, %g) = %g, want %g", tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestStudentTTwoSided(t *testing.T) {
+	tests := []struct {
+		name string
+		t    float64
+		df   float64
+		want float64
+	}{
+		{"zero statistic", 0, 5, 1},
+		{"cauchy at one", 1, 1, 0.5}, // 1 - 2/pi*atan(1)
+		{"cauchy is symmetric", -1, 1, 0.5},
+		{"table critical value", 2.1009, 18, 0.05},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := studentTTwoSided(tt.t, tt.df); !approxEqual(got, tt.want, 1e-4) {
+				t.Errorf("studentTTwoSided(%g, %g) = %g, want %g", tt.t, tt.df, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeanInterval(t *testing.T) {
+	tests := []struct {
+		name      string
+		mean      float64
+		variance  float64
+		n         int64
+		wantLower float64
+		wantUpper float64
+	}{
+		{"single sample", 3, 1, 1, 3, 3},
+		{"no samples", 0, 0, 0, 0, 0},
+		{"five samples", 10, 4, 5, 10 - 2.7764*math.Sqrt(0.8), 10 + 2.7764*math.Sqrt(0.8)},
+		{"zero variance", 7, 0, 20, 7, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lower, upper := MeanInterval(tt.mean, tt.variance, tt.n)
+			if !approxEqual(lower, tt.wantLower, 1e-3) || !approxEqual(upper, tt.wantUpper, 1e-3) {
+				t.Errorf("MeanInterval() = (%g, %g), want (%g, %g)", lower, upper, tt.wantLower, tt.wantUpper)
+			}
+		})
+	}
+}
+
+func TestProportionInterval(t *testing.T) {
+	tests := []struct {
+		name      string
+		successes int64
+		n         int64
+		wantLower float64
+		wantUpper float64
+	}{
+		{"no trials", 0, 0, 0, 0},
+		{"half", 50, 100, 0.4038, 0.5962},
+		{"no successes", 0, 10, 0, 0.2775},
+		{"all successes", 10, 10, 0.7225, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lower, upper := ProportionInterval(tt.successes, tt.n)
+			if !approxEqual(lower, tt.wantLower, 1e-4) || !approxEqual(upper, tt.wantUpper, 1e-4) {
+				t.Errorf("ProportionInterval(%d, %d) = (%g, %g), want (%g, %g)",
+					tt.successes, tt.n, lower, upper, tt.wantLower, tt.wantUpper)
+			}
+			if lower < 0 || upper > 1 {
+				t.Errorf("ProportionInterval(%d, %d) = (%g, %g) leaves [0, 1]", tt.successes, tt.n, lower, upper)
+			}
+		})
+	}
+}
+
+func TestTwoProportionZTest(t *testing.T) {
+	tests := []struct {
+		name       string
+		successesA int64
+		nA         int64
+		successesB int64
+		nB         int64
+		wantZ      float64
+		wantP      float64
+	}{
+		{"equal proportions", 50, 100, 50, 100, 0, 1},
+		{"b higher", 40, 100, 60, 100, 2 * math.Sqrt2, math.Erfc(2)},
+		{"a higher", 60, 100, 40, 100, -2 * math.Sqrt2, math.Erfc(2)},
+		{"empty arm", 0, 0, 5, 10, 0, 1},
+		{"no variance", 10, 10, 20, 20, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z, p := TwoProportionZTest(tt.successesA, tt.nA, tt.successesB, tt.nB)
+			if !approxEqual(z, tt.wantZ, 1e-9) || !approxEqual(p, tt.wantP, 1e-9) {
+				t.Errorf("TwoProportionZTest() = (%g, %g), want (%g, %g)", z, p, tt.wantZ, tt.wantP)
+			}
+		})
+	}
+}
+
+func TestWelchTTest(t *testing.T) {
+	tests := []struct {
+		name   string
+		meanA  float64
+		varA   float64
+		nA     int64
+		meanB  float64
+		varB   float64
+		nB     int64
+		wantT  float64
+		wantDF float64
+		wantP  float64
+	}{
+		{"too few samples", 1, 1, 1, 2, 1, 10, 0, 0, 1},
+		{"no variance", 1, 0, 10, 2, 0, 10, 0, 0, 1},
+		{"equal means", 5, 2, 10, 5, 2, 10, 0, 18, 1},
+		// Equal variances and sizes reduce to Student's t with nA+nB-2 df
+		{"b higher", 0, 1, 10, 1, 1, 10, math.Sqrt(5), 18, 0.0382},
+		// Unequal variances: df from the Welch-Satterthwaite equation
+		{"unequal variances", 0, 4, 5, 0, 1, 20, 0, 0.7225 / (0.64/4 + 0.0025/19), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tStat, df, p := WelchTTest(tt.meanA, tt.varA, tt.nA, tt.meanB, tt.varB, tt.nB)
+			if !approxEqual(tStat, tt.wantT, 1e-9) || !approxEqual(df, tt.wantDF, 1e-9) || !approxEqual(p, tt.wantP, 1e-4) {
+				t.Errorf("WelchTTest() = (%g, %g, %g), want (%g, %g, %g)", tStat, df, p, tt.wantT, tt.wantDF, tt.wantP)
+			}
+		})
+	}
+}
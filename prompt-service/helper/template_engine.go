@@ -2,27 +2,390 @@ package helper
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
 	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode/utf8"
+
+	"github.com/blcvn/backend/services/prompt-service/entities"
+)
+
+const (
+	// MaxTemplateSize bounds the size of template content accepted for parsing
+	MaxTemplateSize = 256 * 1024
+	// MaxRenderedSize bounds the size of a rendered prompt
+	MaxRenderedSize = 1024 * 1024
+	// MaxRenderSteps bounds the range iterations and template calls of a
+	// render, partials included
+	MaxRenderSteps = 100000
+	// MaxTemplateDepth bounds how deeply {{template}} calls can nest
+	MaxTemplateDepth = 32
+	// MaxRenderTime bounds how long a render, partials included, may run
+	MaxRenderTime = 2 * time.Second
 )
 
-type TemplateEngine struct{}
+var (
+	// legacyPlaceholder matches the bare {{name}} syntax of legacy templates
+	legacyPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+	// legacyInclude matches a {{> partial}} include in legacy templates
+	legacyInclude = regexp.MustCompile(`\{\{>\s*([^\s{}]+)\s*\}\}`)
+	// legacyToken matches either, so a single pass never rescans output
+	legacyToken = regexp.MustCompile(legacyInclude.String() + `|` + legacyPlaceholder.String())
+)
+
+// TemplateEngine renders prompt content. Go-syntax templates run through
+// text/template with a curated function map and no access to anything but
+// the supplied values; legacy templates use plain placeholder substitution.
+type TemplateEngine struct {
+	funcs template.FuncMap
+}
 
 func NewTemplateEngine() *TemplateEngine {
-	return &TemplateEngine{}
+	return &TemplateEngine{
+		funcs: template.FuncMap{
+			"join":     joinFunc,
+			"upper":    strings.ToUpper,
+			"lower":    strings.ToLower,
+			"trim":     strings.TrimSpace,
+			"default":  defaultFunc,
+			"truncate": truncateFunc,
+			"json":     jsonFunc,
+			"include":  includeUnresolved,
+		},
+	}
 }
 
-// Render processes a text template with provided variables
-func (e *TemplateEngine) Render(content string, variables map[string]string) (string, error) {
-	tmpl, err := template.New("prompt").Parse(content)
+// Parse compiles Go-syntax content and checks it against the sandbox rules
+func (e *TemplateEngine) Parse(content string) (*template.Template, error) {
+	if len(content) > MaxTemplateSize {
+		return nil, fmt.Errorf("template exceeds %d bytes", MaxTemplateSize)
+	}
+
+	tmpl, err := template.New("prompt").Funcs(e.funcs).Option("missingkey=error").Parse(content)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %w", err)
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil || t.Tree.Root == nil {
+			continue
+		}
+		if err := checkSandbox(t.Tree, t.Tree.Root); err != nil {
+			return nil, err
+		}
 	}
+	return tmpl, nil
+}
+
+// Render processes a Go-syntax text template with provided variables
+func (e *TemplateEngine) Render(content string, variables map[string]interface{}) (string, error) {
+	return e.render(content, variables, nil, 0, newRenderBudget())
+}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, variables); err != nil {
+// RenderLegacy replaces {{name}} placeholders with the matching values.
+// Placeholders without a value are left untouched.
+func (e *TemplateEngine) RenderLegacy(content string, variables map[string]interface{}) (string, error) {
+	return e.renderLegacy(content, variables, nil, 0, newRenderBudget())
+}
+
+// RenderWithPartials renders content of either syntax, expanding includes
+// from partials, which must hold every reference reachable from content.
+func (e *TemplateEngine) RenderWithPartials(content string, syntax entities.TemplateSyntax, variables map[string]interface{}, partials map[string]*Partial) (string, error) {
+	return e.renderSyntax(content, syntax, variables, partials, 0, newRenderBudget())
+}
+
+func (e *TemplateEngine) renderSyntax(content string, syntax entities.TemplateSyntax, variables map[string]interface{}, partials map[string]*Partial, depth int, budget *renderBudget) (string, error) {
+	if syntax == entities.TemplateSyntaxLegacy {
+		return e.renderLegacy(content, variables, partials, depth, budget)
+	}
+	return e.render(content, variables, partials, depth, budget)
+}
+
+func (e *TemplateEngine) render(content string, variables map[string]interface{}, partials map[string]*Partial, depth int, budget *renderBudget) (string, error) {
+	tmpl, err := e.Parse(content)
+	if err != nil {
+		return "", err
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && t.Tree.Root != nil {
+			guardTree(t.Tree)
+		}
+	}
+	tmpl.Funcs(template.FuncMap{
+		"include": func(ref string) (string, error) {
+			return e.renderPartial(ref, variables, partials, depth, budget)
+		},
+		guardRange: budget.rangeOver,
+		guardEnter: budget.enter,
+		guardLeave: budget.leave,
+	})
+
+	buf := &limitedBuffer{limit: MaxRenderedSize, budget: budget}
+	if err := tmpl.Execute(buf, variables); err != nil {
 		return "", fmt.Errorf("failed to execute template: %w", err)
 	}
 
 	return buf.String(), nil
 }
+
+func (e *TemplateEngine) renderLegacy(content string, variables map[string]interface{}, partials map[string]*Partial, depth int, budget *renderBudget) (string, error) {
+	var includeErr error
+	rendered := legacyToken.ReplaceAllStringFunc(content, func(match string) string {
+		groups := legacyToken.FindStringSubmatch(match)
+		if ref := groups[1]; ref != "" {
+			out, err := e.renderPartial(ref, variables, partials, depth, budget)
+			if err != nil && includeErr == nil {
+				includeErr = err
+			}
+			return out
+		}
+		if val, ok := variables[groups[2]]; ok {
+			return stringify(val)
+		}
+		return match
+	})
+	if includeErr != nil {
+		return "", includeErr
+	}
+	if len(rendered) > MaxRenderedSize {
+		return "", fmt.Errorf("rendered prompt exceeds %d bytes", MaxRenderedSize)
+	}
+	return rendered, nil
+}
+
+// checkSandbox rejects constructs that can loop without bound regardless of
+// the supplied values, such as ranging over an integer literal. Values are
+// checked as the template runs; see guardTree.
+func checkSandbox(tree *parse.Tree, node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkSandbox(tree, child); err != nil {
+				return err
+			}
+		}
+	case *parse.RangeNode:
+		for _, cmd := range n.Pipe.Cmds {
+			for _, arg := range cmd.Args {
+				if _, ok := arg.(*parse.NumberNode); ok {
+					location, _ := tree.ErrorContext(n)
+					return fmt.Errorf("%s: range over a number literal is not allowed", location)
+				}
+			}
+		}
+		if err := checkSandbox(tree, n.List); err != nil {
+			return err
+		}
+		return checkSandbox(tree, n.ElseList)
+	case *parse.IfNode:
+		if err := checkSandbox(tree, n.List); err != nil {
+			return err
+		}
+		return checkSandbox(tree, n.ElseList)
+	case *parse.WithNode:
+		if err := checkSandbox(tree, n.List); err != nil {
+			return err
+		}
+		return checkSandbox(tree, n.ElseList)
+	}
+	return nil
+}
+
+// Functions guardTree adds to a template before it runs. Content cannot call
+// them: they are only defined for execution, after parsing.
+const (
+	guardRange = "sandboxRange"
+	guardEnter = "sandboxEnter"
+	guardLeave = "sandboxLeave"
+)
+
+// guardTree instruments a parsed template so the sandbox is enforced while it
+// runs: every range pipeline ends in guardRange, and the body is wrapped in
+// guardEnter and guardLeave to bound {{template}} recursion.
+func guardTree(tree *parse.Tree) {
+	guardNode(tree.Root)
+	pos := tree.Root.Position()
+	nodes := make([]parse.Node, 0, len(tree.Root.Nodes)+2)
+	nodes = append(nodes, guardAction(guardEnter, pos))
+	nodes = append(nodes, tree.Root.Nodes...)
+	nodes = append(nodes, guardAction(guardLeave, pos))
+	tree.Root.Nodes = nodes
+}
+
+func guardNode(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			guardNode(child)
+		}
+	case *parse.RangeNode:
+		n.Pipe.Cmds = append(n.Pipe.Cmds, guardCommand(guardRange, n.Position()))
+		guardNode(n.List)
+		guardNode(n.ElseList)
+	case *parse.IfNode:
+		guardNode(n.List)
+		guardNode(n.ElseList)
+	case *parse.WithNode:
+		guardNode(n.List)
+		guardNode(n.ElseList)
+	}
+}
+
+func guardCommand(name string, pos parse.Pos) *parse.CommandNode {
+	return &parse.CommandNode{
+		NodeType: parse.NodeCommand,
+		Pos:      pos,
+		Args:     []parse.Node{parse.NewIdentifier(name).SetPos(pos)},
+	}
+}
+
+func guardAction(name string, pos parse.Pos) *parse.ActionNode {
+	return &parse.ActionNode{
+		NodeType: parse.NodeAction,
+		Pos:      pos,
+		Pipe: &parse.PipeNode{
+			NodeType: parse.NodePipe,
+			Pos:      pos,
+			Cmds:     []*parse.CommandNode{guardCommand(name, pos)},
+		},
+	}
+}
+
+// renderBudget is shared by a render and the partials it includes, bounding
+// the work they do together
+type renderBudget struct {
+	steps    int
+	depth    int
+	deadline time.Time
+}
+
+func newRenderBudget() *renderBudget {
+	return &renderBudget{deadline: time.Now().Add(MaxRenderTime)}
+}
+
+// spend charges n steps against the budget
+func (b *renderBudget) spend(n int) error {
+	b.steps += n
+	if b.steps > MaxRenderSteps {
+		return fmt.Errorf("render exceeds %d steps", MaxRenderSteps)
+	}
+	if time.Now().After(b.deadline) {
+		return fmt.Errorf("render exceeds %s", MaxRenderTime)
+	}
+	return nil
+}
+
+// rangeOver lets {{range}} iterate over collections only, charging one step
+// per element. Integers and functions could loop without bound.
+func (b *renderBudget) rangeOver(val interface{}) (interface{}, error) {
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return val, nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return val, b.spend(v.Len())
+	}
+	return nil, fmt.Errorf("range over %T is not allowed", val)
+}
+
+func (b *renderBudget) enter() (string, error) {
+	b.depth++
+	if b.depth > MaxTemplateDepth {
+		return "", fmt.Errorf("templates are nested deeper than %d levels", MaxTemplateDepth)
+	}
+	return "", b.spend(1)
+}
+
+func (b *renderBudget) leave() string {
+	b.depth--
+	return ""
+}
+
+// limitedBuffer aborts template execution once the output grows too large or
+// the render runs out of time
+type limitedBuffer struct {
+	bytes.Buffer
+	limit  int
+	budget *renderBudget
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, fmt.Errorf("rendered prompt exceeds %d bytes", b.limit)
+	}
+	if b.budget != nil {
+		if err := b.budget.spend(0); err != nil {
+			return 0, err
+		}
+	}
+	return b.Buffer.Write(p)
+}
+
+func joinFunc(items interface{}, sep string) string {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return stringify(items)
+	}
+	parts := make([]string, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		parts[i] = stringify(v.Index(i).Interface())
+	}
+	return strings.Join(parts, sep)
+}
+
+// defaultFunc returns def when val is empty: {{.tone | default "neutral"}}
+func defaultFunc(def interface{}, val interface{}) interface{} {
+	if val == nil {
+		return def
+	}
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if v.Len() == 0 {
+			return def
+		}
+	}
+	return val
+}
+
+// truncateFunc shortens s to at most n runes: {{.transcript | truncate 2000}}
+func truncateFunc(n int, s interface{}) string {
+	str := stringify(s)
+	if n < 0 || utf8.RuneCountInString(str) <= n {
+		return str
+	}
+	return string([]rune(str)[:n])
+}
+
+func jsonFunc(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func stringify(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(val)
+		return string(b)
+	default:
+		return fmt.Sprint(val)
+	}
+}
@@ -0,0 +1,110 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderSandbox(t *testing.T) {
+	engine := NewTemplateEngine()
+	tests := []struct {
+		name      string
+		content   string
+		variables map[string]interface{}
+		want      string
+		wantErr   string
+	}{
+		{
+			name:      "range within the budget",
+			content:   `{{range .items}}{{.}},{{end}}`,
+			variables: map[string]interface{}{"items": []int{1, 2, 3}},
+			want:      "1,2,3,",
+		},
+		{
+			name:      "range over a missing value",
+			content:   `{{range .items}}x{{else}}none{{end}}`,
+			variables: map[string]interface{}{"items": nil},
+			want:      "none",
+		},
+		{
+			name:      "range beyond the step budget",
+			content:   `{{range .items}}{{end}}`,
+			variables: map[string]interface{}{"items": make([]struct{}, MaxRenderSteps+1)},
+			wantErr:   "steps",
+		},
+		{
+			name:      "nested ranges share the budget",
+			content:   `{{range .rows}}{{range $.cols}}{{end}}{{end}}`,
+			variables: map[string]interface{}{"rows": make([]int, 400), "cols": make([]int, 400)},
+			wantErr:   "steps",
+		},
+		{
+			name:      "range over an integer value",
+			content:   `{{range .n}}x{{end}}`,
+			variables: map[string]interface{}{"n": 5},
+			wantErr:   "not allowed",
+		},
+		{
+			name:    "range over an integer literal",
+			content: `{{range 1000000}}x{{end}}`,
+			wantErr: "number literal",
+		},
+		{
+			name:    "unbounded recursion",
+			content: `{{define "loop"}}{{template "loop"}}{{end}}{{template "loop"}}`,
+			wantErr: "nested deeper",
+		},
+		{
+			name:    "bounded recursion",
+			content: `{{define "r"}}{{if .}}x{{template "r" slice . 1}}{{end}}{{end}}{{template "r" "abc"}}`,
+			want:    "xxx",
+		},
+		{
+			name:      "output size",
+			content:   `{{range .items}}{{$.chunk}}{{end}}`,
+			variables: map[string]interface{}{"items": make([]int, 2), "chunk": strings.Repeat("x", MaxRenderedSize/2+1)},
+			wantErr:   "bytes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := engine.Render(tt.content, tt.variables)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Render() error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderLegacy(t *testing.T) {
+	engine := NewTemplateEngine()
+	tests := []struct {
+		content   string
+		variables map[string]interface{}
+		want      string
+	}{
+		{"Hello {{name}}", map[string]interface{}{"name": "An"}, "Hello An"},
+		{"Hello {{ name }}", map[string]interface{}{"name": "An"}, "Hello An"},
+		{"Hello {{name}}", nil, "Hello {{name}}"},
+		// Values are not rescanned for placeholders
+		{"{{a}}{{b}}", map[string]interface{}{"a": "{{b}}", "b": "B"}, "{{b}}B"},
+	}
+	for _, tt := range tests {
+		got, err := engine.RenderLegacy(tt.content, tt.variables)
+		if err != nil {
+			t.Fatalf("RenderLegacy(%q) error = %v", tt.content, err)
+		}
+		if got != tt.want {
+			t.Errorf("RenderLegacy(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
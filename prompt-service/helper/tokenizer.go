@@ -0,0 +1,313 @@
+package helper
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer estimates how many tokens a model reads for a piece of text
+type Tokenizer interface {
+	Name() string
+	CountTokens(text string) int
+}
+
+// DefaultVocabulary is the BPE vocabulary bundled with the service
+const DefaultVocabulary = "cl100k_base"
+
+//go:embed vocab/cl100k_base.tiktoken
+var cl100kBase []byte
+
+// vocabularyChecksums are the published sha256 sums of the tiktoken
+// vocabularies; a vocabulary with a known name must match its sum.
+var vocabularyChecksums = map[string]string{
+	"cl100k_base": "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+}
+
+// NewTokenizer loads the BPE vocabulary at vocabPath, a file in the tiktoken
+// format such as cl100k_base.tiktoken, or the bundled cl100k_base when
+// vocabPath is empty. When the vocabulary cannot be loaded the heuristic
+// tokenizer is returned together with the reason.
+func NewTokenizer(vocabPath string) (Tokenizer, error) {
+	var bpe *BPETokenizer
+	var err error
+	if vocabPath == "" {
+		bpe, err = parseBPETokenizer(DefaultVocabulary, cl100kBase)
+	} else {
+		bpe, err = LoadBPETokenizer(vocabPath)
+	}
+	if err != nil {
+		return HeuristicTokenizer{}, err
+	}
+	return bpe, nil
+}
+
+// HeuristicTokenizer approximates token counts without a vocabulary. It
+// splits text the way BPE tokenizers do before merging and assumes about
+// four bytes per token within each piece, which tends to overestimate
+// English slightly and underestimate heavily accented text.
+type HeuristicTokenizer struct{}
+
+func (HeuristicTokenizer) Name() string { return "heuristic" }
+
+func (HeuristicTokenizer) CountTokens(text string) int {
+	count := 0
+	for _, piece := range pretokenize(text) {
+		count += int(math.Ceil(float64(len(piece)) / 4))
+	}
+	return count
+}
+
+// BPETokenizer counts tokens with byte-pair encoding over a ranked
+// vocabulary, matching the encodings used by OpenAI models.
+type BPETokenizer struct {
+	name  string
+	ranks map[string]int
+}
+
+// LoadBPETokenizer reads a tiktoken vocabulary: one base64 token and its
+// merge rank per line.
+func LoadBPETokenizer(path string) (*BPETokenizer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vocabulary: %w", err)
+	}
+	return parseBPETokenizer(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), data)
+}
+
+func parseBPETokenizer(name string, data []byte) (*BPETokenizer, error) {
+	if want, ok := vocabularyChecksums[name]; ok {
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); got != want {
+			return nil, fmt.Errorf("vocabulary %s has sha256 %s, want %s", name, got, want)
+		}
+	}
+
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		token, rank, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if !ok {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("vocabulary line %d: %w", line, err)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("vocabulary line %d: invalid rank %q", line, rank)
+		}
+		ranks[string(decoded)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read vocabulary: %w", err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("vocabulary %s is empty", name)
+	}
+
+	return &BPETokenizer{name: name, ranks: ranks}, nil
+}
+
+func (t *BPETokenizer) Name() string { return t.name }
+
+func (t *BPETokenizer) CountTokens(text string) int {
+	count := 0
+	for _, piece := range pretokenize(text) {
+		if _, ok := t.ranks[piece]; ok {
+			count++
+			continue
+		}
+		count += t.mergeCount(piece)
+	}
+	return count
+}
+
+// mergeCount applies BPE to a piece: starting from single bytes, the adjacent
+// pair with the lowest rank, leftmost first, is merged until no pair is in
+// the vocabulary. Candidate pairs are kept in a heap so long pieces cost
+// O(n log n) rather than a rescan per merge.
+func (t *BPETokenizer) mergeCount(piece string) int {
+	n := len(piece)
+	if n < 2 {
+		return n
+	}
+	// Parts form a linked list over piece; a part starts at its index and
+	// ends where next starts
+	next := make([]int, n)
+	prev := make([]int, n)
+	for i := range next {
+		next[i], prev[i] = i+1, i-1
+	}
+	end := func(i int) int {
+		if i >= n {
+			return n
+		}
+		return next[i]
+	}
+
+	pairs := &mergeHeap{}
+	push := func(i int) {
+		if i < 0 || next[i] >= n {
+			return
+		}
+		stop := end(next[i])
+		if rank, ok := t.ranks[piece[i:stop]]; ok {
+			heap.Push(pairs, mergeCandidate{rank: rank, start: i, end: stop})
+		}
+	}
+	for i := 0; i < n-1; i++ {
+		push(i)
+	}
+
+	parts := n
+	for pairs.Len() > 0 {
+		c := heap.Pop(pairs).(mergeCandidate)
+		// Skip pairs that no longer exist: merged away, or grown since
+		if next[c.start] < 0 || next[c.start] >= n || end(next[c.start]) != c.end {
+			continue
+		}
+		right := next[c.start]
+		next[c.start] = next[right]
+		if next[right] < n {
+			prev[next[right]] = c.start
+		}
+		next[right] = -1
+		parts--
+		push(prev[c.start])
+		push(c.start)
+	}
+	return parts
+}
+
+// mergeCandidate is a pair of adjacent parts, piece[start:end], that could
+// be merged
+type mergeCandidate struct {
+	rank  int
+	start int
+	end   int
+}
+
+// mergeHeap orders candidates by rank, then position
+type mergeHeap []mergeCandidate
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank < h[j].rank
+	}
+	return h[i].start < h[j].start
+}
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(mergeCandidate)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// pretokenize splits text into the pieces BPE runs on, following the
+// cl100k_base pattern:
+//
+//	's|'t|'re|'ve|'m|'ll|'d | [^\r\n\p{L}\p{N}]?\p{L}+ | \p{N}{1,3} |
+//	 ?[^\s\p{L}\p{N}]+[\r\n]* | \s*[\r\n]+ | \s+(?!\S) | \s+
+//
+// It is written out by hand because Go's regexp has no lookahead.
+func pretokenize(text string) []string {
+	var pieces []string
+	for i := 0; i < len(text); {
+		n := pieceLength(text[i:])
+		pieces = append(pieces, text[i:i+n])
+		i += n
+	}
+	return pieces
+}
+
+func pieceLength(s string) int {
+	r, size := utf8.DecodeRuneInString(s)
+
+	// Contractions
+	if r == '\'' {
+		lower := strings.ToLower(s[1:min(len(s), 3)])
+		for _, suffix := range []string{"s", "t", "re", "ve", "m", "ll", "d"} {
+			if strings.HasPrefix(lower, suffix) {
+				return 1 + len(suffix)
+			}
+		}
+	}
+
+	// A word with at most one leading non-letter, non-digit character
+	if unicode.IsLetter(r) {
+		return size + spanOf(s[size:], unicode.IsLetter)
+	}
+	if r != '\r' && r != '\n' && !unicode.IsNumber(r) {
+		if n := spanOf(s[size:], unicode.IsLetter); n > 0 {
+			return size + n
+		}
+	}
+
+	// Up to three digits
+	if unicode.IsNumber(r) {
+		n, digits := 0, 0
+		for _, c := range s {
+			if !unicode.IsNumber(c) || digits == 3 {
+				break
+			}
+			n += utf8.RuneLen(c)
+			digits++
+		}
+		return n
+	}
+
+	// Punctuation, optionally after a space, with trailing newlines
+	punct := func(c rune) bool { return !unicode.IsSpace(c) && !unicode.IsLetter(c) && !unicode.IsNumber(c) }
+	start := 0
+	if r == ' ' {
+		start = 1
+	}
+	if n := spanOf(s[start:], punct); n > 0 {
+		end := start + n
+		return end + spanOf(s[end:], func(c rune) bool { return c == '\r' || c == '\n' })
+	}
+
+	// Whitespace: up to the last newline, else all but the space before the
+	// next word, else all of it
+	ws := spanOf(s, unicode.IsSpace)
+	if last := strings.LastIndexAny(s[:ws], "\r\n"); last >= 0 {
+		return last + 1
+	}
+	if ws < len(s) {
+		_, lastSize := utf8.DecodeLastRuneInString(s[:ws])
+		if ws-lastSize > 0 {
+			return ws - lastSize
+		}
+	}
+	if ws > 0 {
+		return ws
+	}
+	return size
+}
+
+// spanOf returns the length in bytes of the prefix of s whose runes satisfy f
+func spanOf(s string, f func(rune) bool) int {
+	for i, c := range s {
+		if !f(c) {
+			return i
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,138 @@
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPretokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"Hello, world!", []string{"Hello", ",", " world", "!"}},
+		{"I'm here", []string{"I", "'m", " here"}},
+		{"WE'LL", []string{"WE", "'LL"}},
+		{"year 12345", []string{"year", " ", "123", "45"}},
+		{"foo  bar", []string{"foo", " ", " bar"}},
+		{"a\n\nb", []string{"a", "\n\n", "b"}},
+		{"end.\n", []string{"end", ".\n"}},
+		{"trailing   ", []string{"trailing", "   "}},
+		{"xin chào", []string{"xin", " chào"}},
+	}
+	for _, tt := range tests {
+		if got := pretokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("pretokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestHeuristicTokenizer(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"Hello, world!", 6},
+		{"a b c", 3},
+	}
+	for _, tt := range tests {
+		if got := (HeuristicTokenizer{}).CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBundledTokenizer(t *testing.T) {
+	tokenizer, err := NewTokenizer("")
+	if err != nil {
+		t.Fatalf("NewTokenizer() error = %v", err)
+	}
+	if tokenizer.Name() != DefaultVocabulary {
+		t.Fatalf("Name() = %s, want %s", tokenizer.Name(), DefaultVocabulary)
+	}
+
+	// Counts as reported by tiktoken for cl100k_base
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello world", 2},
+		{"Hello, world!", 4},
+		{"tiktoken is great!", 6},
+	}
+	for _, tt := range tests {
+		if got := tokenizer.CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBPEMerges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tiny.tiktoken")
+	// "a", "b", "ab" and "c"; "abab" is not in the vocabulary
+	vocab := "YQ== 0\nYg== 1\nYWI= 2\nYw== 3\n"
+	if err := os.WriteFile(path, []byte(vocab), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tokenizer, err := LoadBPETokenizer(path)
+	if err != nil {
+		t.Fatalf("LoadBPETokenizer() error = %v", err)
+	}
+	if tokenizer.Name() != "tiny" {
+		t.Errorf("Name() = %s, want tiny", tokenizer.Name())
+	}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"ab", 1},
+		{"abab", 2},
+		{"abc", 2},
+		{"cab", 2},
+		{"xyz", 3}, // unknown bytes stay single tokens
+	}
+	for _, tt := range tests {
+		if got := tokenizer.CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLoadBPETokenizerErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		vocab string
+	}{
+		{"checksum mismatch", "cl100k_base.tiktoken", "YQ== 0\n"},
+		{"invalid base64", "bad.tiktoken", "!!! 0\n"},
+		{"invalid rank", "bad.tiktoken", "YQ== first\n"},
+		{"empty", "empty.tiktoken", "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.vocab), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadBPETokenizer(path); err == nil {
+				t.Error("LoadBPETokenizer() error = nil, want an error")
+			}
+		})
+	}
+
+	tokenizer, err := NewTokenizer(filepath.Join(t.TempDir(), "missing.tiktoken"))
+	if err == nil {
+		t.Error("NewTokenizer() error = nil for a missing vocabulary")
+	}
+	if tokenizer.Name() != "heuristic" {
+		t.Errorf("NewTokenizer() fell back to %s, want heuristic", tokenizer.Name())
+	}
+}
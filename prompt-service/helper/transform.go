@@ -1,11 +1,22 @@
 package helper
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
 	"github.com/blcvn/backend/services/prompt-service/entities"
 	pb "github.com/blcvn/kratos-proto/go/prompt"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// VariableSchemaKeyPrefix marks template metadata entries holding the JSON
+// Schema of a variable, e.g. "schema:requirements".
+const VariableSchemaKeyPrefix = "schema:"
+
+// TagSeparator joins template tags in the "tags" metadata entry
+const TagSeparator = ","
+
 type Transform struct{}
 
 func NewTransform() *Transform { return &Transform{} }
@@ -22,6 +33,38 @@ func (t *Transform) Template2Pb(entity *entities.PromptTemplate) *pb.PromptTempl
 		}
 	}
 
+	metadata := map[string]string{ // Use metadata for fields missing from the proto
+		"description": entity.Description,
+		"locale":      entity.Locale,
+		"syntax":      string(entity.Syntax),
+		"kind":        string(entity.Kind),
+		"tags":        strings.Join(entity.Tags, TagSeparator),
+		"status":      string(entity.Status), // the proto has no draft and review statuses
+	}
+	if len(entity.Lint) > 0 {
+		warnings := make([]string, len(entity.Lint))
+		for i, issue := range entity.Lint {
+			warnings[i] = issue.String()
+		}
+		metadata["lint"] = strings.Join(warnings, "; ")
+	}
+	if entity.TenantID != "" {
+		metadata["tenant_id"] = entity.TenantID
+	}
+	if entity.Locales != nil {
+		metadata["locales"] = strings.Join(entity.Locales, ",")
+		metadata["missing_locales"] = strings.Join(entity.MissingLocales, ",")
+	}
+	if entity.Parameters != nil {
+		params, _ := json.Marshal(entity.Parameters)
+		metadata["parameters"] = string(params)
+	}
+	for _, v := range entity.Variables {
+		if v.Schema != "" {
+			metadata[VariableSchemaKeyPrefix+v.Name] = v.Schema
+		}
+	}
+
 	return &pb.PromptTemplate{
 		Id:   entity.ID,
 		Name: entity.Name,
@@ -29,14 +72,41 @@ func (t *Transform) Template2Pb(entity *entities.PromptTemplate) *pb.PromptTempl
 		Version:   entity.Version,
 		Template:  entity.Content, // Mapped to Content
 		Variables: vars,
-		// Tags:        entity.Tags, // Not in proto
-		Metadata:  map[string]string{"description": entity.Description}, // Use metadata for desc
-		Status:    pb.TemplateStatus(pb.TemplateStatus_value[string(entity.Status)]),
+		Metadata:  metadata,
+		Status:    t.Status2Pb(entity.Status),
 		CreatedAt: timestamppb.New(entity.CreatedAt),
 		UpdatedAt: timestamppb.New(entity.UpdatedAt),
 	}
 }
 
+// Status2Pb maps a status onto the proto enum, where drafts and templates
+// under review are both inactive
+func (t *Transform) Status2Pb(status entities.TemplateStatus) pb.TemplateStatus {
+	switch status {
+	case entities.TemplateStatusActive:
+		return pb.TemplateStatus_ACTIVE
+	case entities.TemplateStatusDraft, entities.TemplateStatusReview:
+		return pb.TemplateStatus_INACTIVE
+	case entities.TemplateStatusArchived:
+		return pb.TemplateStatus_ARCHIVED
+	}
+	return pb.TemplateStatus_TEMPLATE_UNSPECIFIED
+}
+
+// Pb2Status maps a proto status to the entity one; inactive means draft and
+// unspecified maps to "", leaving the status unchanged or unfiltered
+func (t *Transform) Pb2Status(status pb.TemplateStatus) entities.TemplateStatus {
+	switch status {
+	case pb.TemplateStatus_ACTIVE:
+		return entities.TemplateStatusActive
+	case pb.TemplateStatus_INACTIVE:
+		return entities.TemplateStatusDraft
+	case pb.TemplateStatus_ARCHIVED:
+		return entities.TemplateStatusArchived
+	}
+	return ""
+}
+
 func (t *Transform) Pb2Variable(pbVars []*pb.Variable) []entities.Variable {
 	vars := make([]entities.Variable, len(pbVars))
 	for i, v := range pbVars {
@@ -50,3 +120,126 @@ func (t *Transform) Pb2Variable(pbVars []*pb.Variable) []entities.Variable {
 	}
 	return vars
 }
+
+// MetadataTags splits the comma-separated "tags" metadata entry
+func (t *Transform) MetadataTags(metadata map[string]string) []string {
+	raw, ok := metadata["tags"]
+	if !ok || strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	return strings.Split(raw, TagSeparator)
+}
+
+// MetadataParameters decodes the JSON "parameters" metadata entry
+func (t *Transform) MetadataParameters(metadata map[string]string) (*entities.ModelParameters, error) {
+	raw, ok := metadata["parameters"]
+	if !ok || strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var params entities.ModelParameters
+	if err := json.Unmarshal([]byte(raw), &params); err != nil {
+		return nil, fmt.Errorf("invalid parameters metadata: %w", err)
+	}
+	return &params, nil
+}
+
+// ApplyVariableSchemas copies "schema:<name>" metadata entries onto the
+// matching variables, since pb.Variable has no schema field.
+func (t *Transform) ApplyVariableSchemas(vars []entities.Variable, metadata map[string]string) {
+	for i := range vars {
+		if schema, ok := metadata[VariableSchemaKeyPrefix+vars[i].Name]; ok {
+			vars[i].Schema = schema
+		}
+	}
+}
+
+// KeepStoredVariables restores what the proto cannot carry from the current
+// variable of the same name: its schema, unless one is sent, and whether it
+// was inferred, if it is sent back unchanged.
+func (t *Transform) KeepStoredVariables(vars []entities.Variable, current []entities.Variable) {
+	stored := make(map[string]entities.Variable, len(current))
+	for _, v := range current {
+		stored[v.Name] = v
+	}
+	for i := range vars {
+		v, ok := stored[vars[i].Name]
+		if !ok {
+			continue
+		}
+		if vars[i].Schema == "" {
+			vars[i].Schema = v.Schema
+		}
+		if v.Inferred {
+			sent := vars[i]
+			sent.Inferred = true
+			vars[i].Inferred = sent == v
+		}
+	}
+}
+
+// WinnerVariantKey maps the winner of a proto request, a variant key or the
+// template of exactly one variant, to the variant key
+func (t *Transform) WinnerVariantKey(experiment *entities.PromptExperiment, winner string) (string, error) {
+	if _, ok := experiment.Variant(winner); ok {
+		return winner, nil
+	}
+	var keys []string
+	for _, v := range experiment.Variants {
+		if v.TemplateID == winner {
+			keys = append(keys, v.Key)
+		}
+	}
+	switch len(keys) {
+	case 0:
+		return "", fmt.Errorf("winner %s is not a variant of this experiment", winner)
+	case 1:
+		return keys[0], nil
+	}
+	return "", fmt.Errorf("template %s is used by variants %s; name the winner by variant key", winner, strings.Join(keys, ", "))
+}
+
+// Experiment2Pb maps an experiment onto the two-armed proto message. The
+// traffic split is the share of traffic, in percent, sent to variant B.
+func (t *Transform) Experiment2Pb(entity *entities.PromptExperiment) *pb.PromptExperiment {
+	experiment := &pb.PromptExperiment{
+		Id:        entity.ID,
+		Name:      entity.Name,
+		Status:    string(entity.Status),
+		CreatedAt: timestamppb.New(entity.CreatedAt),
+	}
+
+	total := 0
+	for _, v := range entity.Variants {
+		total += v.Weight
+	}
+	if len(entity.Variants) > 0 {
+		experiment.TemplateAId = entity.Variants[0].TemplateID
+	}
+	if len(entity.Variants) > 1 {
+		experiment.TemplateBId = entity.Variants[1].TemplateID
+		if total > 0 {
+			experiment.TrafficSplit = int32(entity.Variants[1].Weight * 100 / total)
+		}
+	}
+	if winner, ok := entity.Variant(entity.WinnerVariant); ok {
+		experiment.WinnerId = winner.TemplateID
+	}
+	if entity.CompletedAt != nil {
+		experiment.CompletedAt = timestamppb.New(*entity.CompletedAt)
+	}
+	return experiment
+}
+
+// Pb2ExperimentVariants builds the A/B variants of a proto experiment request
+func (t *Transform) Pb2ExperimentVariants(payload *pb.CreateExperimentPayload) []entities.ExperimentVariant {
+	split := int(payload.TrafficSplit)
+	if split <= 0 {
+		split = 50 // unset, split evenly
+	} else if split > 100 {
+		split = 100
+	}
+	return []entities.ExperimentVariant{
+		{Key: "a", Name: "A", TemplateID: payload.TemplateAId, Weight: 100 - split},
+		{Key: "b", Name: "B", TemplateID: payload.TemplateBId, Weight: split},
+	}
+}
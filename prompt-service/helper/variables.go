@@ -0,0 +1,197 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/blcvn/backend/services/prompt-service/entities"
+)
+
+// ValidateVariableDeclarations checks declared variables when a template is
+// saved: known types, unique names, parseable schemas and valid defaults.
+func ValidateVariableDeclarations(vars []entities.Variable) []string {
+	var errs []string
+	seen := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		if v.Name == "" {
+			errs = append(errs, "variable name is required")
+			continue
+		}
+		if seen[v.Name] {
+			errs = append(errs, fmt.Sprintf("%s: declared more than once", v.Name))
+		}
+		seen[v.Name] = true
+
+		switch v.Type {
+		case "", entities.VariableTypeString, entities.VariableTypeNumber, entities.VariableTypeBoolean, entities.VariableTypeJSON:
+		default:
+			errs = append(errs, fmt.Sprintf("%s: unsupported type %q", v.Name, v.Type))
+			continue
+		}
+		if v.Schema != "" {
+			if _, err := ParseJSONSchema(v.Schema); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", v.Name, err))
+				continue
+			}
+		}
+		if v.DefaultValue != "" {
+			if _, verrs := coerceVariable(v, v.DefaultValue); len(verrs) > 0 {
+				errs = append(errs, prefixAll(v.Name+" default", verrs)...)
+			}
+		}
+	}
+	return errs
+}
+
+// InferVariables declares each referenced variable once, in order of first
+// use. Variables are required unless some reference is conditional, and
+// strings unless some reference is structured, which makes them JSON.
+func InferVariables(refs []VariableRef) []entities.Variable {
+	var vars []entities.Variable
+	index := make(map[string]int)
+	for _, ref := range refs {
+		i, seen := index[ref.Name]
+		if !seen {
+			i = len(vars)
+			index[ref.Name] = i
+			vars = append(vars, entities.Variable{Name: ref.Name, Type: entities.VariableTypeString, Required: true, Inferred: true})
+		}
+		if ref.Conditional {
+			vars[i].Required = false
+		}
+		if ref.Structured {
+			vars[i].Type = entities.VariableTypeJSON
+		}
+	}
+	return vars
+}
+
+// DeclaredVariables drops the inferred variables, which are inferred again
+// from the content each time it is saved rather than kept.
+func DeclaredVariables(vars []entities.Variable) []entities.Variable {
+	declared := make([]entities.Variable, 0, len(vars))
+	for _, v := range vars {
+		if !v.Inferred {
+			declared = append(declared, v)
+		}
+	}
+	return declared
+}
+
+// MergeVariables returns declared followed by the inferred variables it does
+// not declare. Declarations always win over what was inferred.
+func MergeVariables(declared []entities.Variable, inferred []entities.Variable) []entities.Variable {
+	merged := make([]entities.Variable, 0, len(declared)+len(inferred))
+	merged = append(merged, declared...)
+	names := make(map[string]bool, len(declared))
+	for _, v := range declared {
+		names[v.Name] = true
+	}
+	for _, v := range inferred {
+		if !names[v.Name] {
+			merged = append(merged, v)
+		}
+	}
+	return merged
+}
+
+// CoerceVariables validates supplied values against their declared types and
+// converts them into the values handed to the template engine. Missing
+// optional variables fall back to their default. Every problem is collected
+// so callers can report them all at once.
+func CoerceVariables(declared []entities.Variable, supplied map[string]string) (map[string]interface{}, []string) {
+	values := make(map[string]interface{}, len(supplied)+len(declared))
+	for k, v := range supplied {
+		values[k] = v
+	}
+
+	var errs []string
+	for _, v := range declared {
+		raw, ok := supplied[v.Name]
+		if !ok {
+			if v.DefaultValue == "" {
+				if v.Required {
+					errs = append(errs, fmt.Sprintf("missing required variable: %s", v.Name))
+				} else {
+					values[v.Name] = zeroValue(v)
+				}
+				continue
+			}
+			raw = v.DefaultValue
+		}
+
+		value, verrs := coerceVariable(v, raw)
+		if len(verrs) > 0 {
+			errs = append(errs, prefixAll(v.Name, verrs)...)
+			continue
+		}
+		values[v.Name] = value
+	}
+	return values, errs
+}
+
+func coerceVariable(v entities.Variable, raw string) (interface{}, []string) {
+	var value interface{}
+	switch v.Type {
+	case entities.VariableTypeNumber:
+		f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, []string{fmt.Sprintf("%q is not a number", raw)}
+		}
+		// Integral values stay integers so {{if gt .count 3}} compares cleanly
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			value = int64(f)
+		} else {
+			value = f
+		}
+	case entities.VariableTypeBoolean:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, []string{fmt.Sprintf("%q is not a boolean", raw)}
+		}
+		value = b
+	case entities.VariableTypeJSON:
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			return nil, []string{fmt.Sprintf("invalid JSON: %v", err)}
+		}
+	default:
+		value = raw
+	}
+
+	if v.Schema != "" {
+		schema, err := ParseJSONSchema(v.Schema)
+		if err != nil {
+			return nil, []string{err.Error()}
+		}
+		if errs := schema.Validate(schemaValue(value)); len(errs) > 0 {
+			return nil, errs
+		}
+	}
+	return value, nil
+}
+
+// schemaValue converts a coerced value to its decoded-JSON representation
+func schemaValue(value interface{}) interface{} {
+	if n, ok := value.(int64); ok {
+		return float64(n)
+	}
+	return value
+}
+
+func zeroValue(v entities.Variable) interface{} {
+	if v.Type == "" || v.Type == entities.VariableTypeString {
+		return ""
+	}
+	return nil
+}
+
+func prefixAll(prefix string, errs []string) []string {
+	out := make([]string, len(errs))
+	for i, e := range errs {
+		out[i] = prefix + ": " + e
+	}
+	return out
+}
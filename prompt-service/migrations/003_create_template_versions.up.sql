@@ -0,0 +1,21 @@
+-- Immutable revisions of prompt templates
+CREATE TABLE IF NOT EXISTS prompt_template_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    template_id UUID NOT NULL REFERENCES prompt_templates(id) ON DELETE CASCADE,
+    version_number INTEGER NOT NULL,
+    version VARCHAR(50) NOT NULL,
+    content TEXT NOT NULL,
+    variables JSONB DEFAULT '[]',
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (template_id, version_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_template_versions_template_id ON prompt_template_versions(template_id);
+
+-- Backfill the current state of existing templates as their first revision
+INSERT INTO prompt_template_versions (template_id, version_number, version, content, variables, created_at)
+SELECT id, 1, 'v1', content, variables, updated_at
+FROM prompt_templates
+ON CONFLICT (template_id, version_number) DO NOTHING;
+
+UPDATE prompt_templates SET version = 'v1';
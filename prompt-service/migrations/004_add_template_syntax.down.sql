@@ -0,0 +1,2 @@
+ALTER TABLE prompt_template_versions DROP COLUMN IF EXISTS syntax;
+ALTER TABLE prompt_templates DROP COLUMN IF EXISTS syntax;
@@ -0,0 +1,12 @@
+-- Per-template rendering syntax: 'go' (text/template) or 'legacy' ({{name}})
+ALTER TABLE prompt_templates ADD COLUMN IF NOT EXISTS syntax VARCHAR(20) DEFAULT 'go';
+ALTER TABLE prompt_template_versions ADD COLUMN IF NOT EXISTS syntax VARCHAR(20) DEFAULT 'go';
+
+-- Content using bare {{name}} placeholders was written for the legacy renderer
+UPDATE prompt_templates SET syntax = 'legacy'
+WHERE content ~ '\{\{\s*[A-Za-z_][A-Za-z0-9_.-]*\s*\}\}'
+  AND content !~ '\{\{-?\s*[.$]';
+
+UPDATE prompt_template_versions SET syntax = 'legacy'
+WHERE content ~ '\{\{\s*[A-Za-z_][A-Za-z0-9_.-]*\s*\}\}'
+  AND content !~ '\{\{-?\s*[.$]';
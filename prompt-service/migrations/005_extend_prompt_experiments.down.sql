@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_experiments_template_id;
+DROP INDEX IF EXISTS idx_experiments_status;
+
+ALTER TABLE prompt_experiments DROP COLUMN IF EXISTS completed_at;
+ALTER TABLE prompt_experiments DROP COLUMN IF EXISTS started_at;
+ALTER TABLE prompt_experiments DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE prompt_experiments DROP COLUMN IF EXISTS winner_variant;
+ALTER TABLE prompt_experiments DROP COLUMN IF EXISTS variants;
+ALTER TABLE prompt_experiments DROP COLUMN IF EXISTS description;
+ALTER TABLE prompt_experiments ALTER COLUMN status SET DEFAULT 'active';
@@ -0,0 +1,28 @@
+-- Multi-variant experiments with an explicit lifecycle
+ALTER TABLE prompt_experiments ALTER COLUMN model_id TYPE VARCHAR(255) USING model_id::text;
+ALTER TABLE prompt_experiments ALTER COLUMN model_id DROP NOT NULL;
+ALTER TABLE prompt_experiments ALTER COLUMN status SET DEFAULT 'draft';
+
+ALTER TABLE prompt_experiments ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE prompt_experiments ADD COLUMN IF NOT EXISTS variants JSONB DEFAULT '[]';
+ALTER TABLE prompt_experiments ADD COLUMN IF NOT EXISTS winner_variant VARCHAR(100);
+ALTER TABLE prompt_experiments ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT NOW();
+ALTER TABLE prompt_experiments ADD COLUMN IF NOT EXISTS started_at TIMESTAMP;
+ALTER TABLE prompt_experiments ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP;
+
+-- Existing rows were single-template experiments that ran immediately
+UPDATE prompt_experiments
+SET variants = jsonb_build_array(jsonb_build_object(
+        'key', 'control',
+        'name', 'Control',
+        'templateId', prompt_template_id::text,
+        'templateVersion', '',
+        'modelId', COALESCE(model_id, ''),
+        'config', config,
+        'weight', 100)),
+    status = CASE WHEN status = 'active' THEN 'running' ELSE status END,
+    started_at = created_at
+WHERE variants = '[]'::jsonb;
+
+CREATE INDEX IF NOT EXISTS idx_experiments_status ON prompt_experiments(status);
+CREATE INDEX IF NOT EXISTS idx_experiments_template_id ON prompt_experiments(prompt_template_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS prompt_experiment_assignments;
+
+ALTER TABLE prompt_experiments DROP COLUMN IF EXISTS overrides;
+ALTER TABLE prompt_experiments DROP COLUMN IF EXISTS holdout_percent;
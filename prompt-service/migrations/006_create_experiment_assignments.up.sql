@@ -0,0 +1,18 @@
+-- Holdout share and QA overrides on experiments
+ALTER TABLE prompt_experiments ADD COLUMN IF NOT EXISTS holdout_percent INTEGER DEFAULT 0;
+ALTER TABLE prompt_experiments ADD COLUMN IF NOT EXISTS overrides JSONB DEFAULT '{}';
+
+-- Sticky variant assignment per experiment subject
+CREATE TABLE IF NOT EXISTS prompt_experiment_assignments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    experiment_id UUID NOT NULL REFERENCES prompt_experiments(id) ON DELETE CASCADE,
+    subject_key VARCHAR(255) NOT NULL,
+    variant_key VARCHAR(100) NOT NULL,
+    holdout BOOLEAN DEFAULT FALSE,
+    forced BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (experiment_id, subject_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_assignments_experiment_variant ON prompt_experiment_assignments(experiment_id, variant_key);
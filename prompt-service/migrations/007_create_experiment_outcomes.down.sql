@@ -0,0 +1 @@
+DROP TABLE IF EXISTS prompt_experiment_outcomes;
@@ -0,0 +1,15 @@
+-- Outcomes observed for experiment assignments
+CREATE TABLE IF NOT EXISTS prompt_experiment_outcomes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    experiment_id UUID NOT NULL REFERENCES prompt_experiments(id) ON DELETE CASCADE,
+    assignment_id UUID NOT NULL REFERENCES prompt_experiment_assignments(id) ON DELETE CASCADE,
+    variant_key VARCHAR(100) NOT NULL,
+    holdout BOOLEAN DEFAULT FALSE,
+    metric VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    value DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_outcomes_experiment_metric ON prompt_experiment_outcomes(experiment_id, metric);
+CREATE INDEX IF NOT EXISTS idx_outcomes_assignment_id ON prompt_experiment_outcomes(assignment_id);
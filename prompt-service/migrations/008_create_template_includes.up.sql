@@ -0,0 +1,9 @@
+-- Partials included by the current content of each template
+CREATE TABLE IF NOT EXISTS prompt_template_includes (
+    template_id UUID NOT NULL REFERENCES prompt_templates(id) ON DELETE CASCADE,
+    partial_name VARCHAR(255) NOT NULL,
+    partial_version VARCHAR(50) NOT NULL DEFAULT '',
+    PRIMARY KEY (template_id, partial_name, partial_version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_template_includes_partial_name ON prompt_template_includes(partial_name);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_prompt_templates_tags;
@@ -0,0 +1,4 @@
+-- Tags are matched with JSONB containment (tags @> '["..."]')
+UPDATE prompt_templates SET tags = '[]' WHERE tags IS NULL OR jsonb_typeof(tags) <> 'array';
+
+CREATE INDEX IF NOT EXISTS idx_prompt_templates_tags ON prompt_templates USING GIN (tags jsonb_path_ops);
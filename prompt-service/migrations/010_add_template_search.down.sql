@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_prompt_templates_search;
+
+ALTER TABLE prompt_templates DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,11 @@
+-- Full-text search over templates: the name ranks above the description,
+-- which ranks above the content. The simple configuration keeps words as
+-- written, which works for both Vietnamese and English prompts.
+ALTER TABLE prompt_templates ADD COLUMN IF NOT EXISTS search_vector TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(description, '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(content, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_prompt_templates_search ON prompt_templates USING GIN (search_vector);
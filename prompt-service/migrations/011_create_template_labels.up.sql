@@ -0,0 +1,16 @@
+-- Named labels (production, staging, canary, ...) pointing at template versions
+CREATE TABLE IF NOT EXISTS prompt_template_labels (
+    template_id UUID NOT NULL REFERENCES prompt_templates(id) ON DELETE CASCADE,
+    label VARCHAR(50) NOT NULL,
+    version_number INTEGER NOT NULL,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (template_id, label),
+    FOREIGN KEY (template_id, version_number) REFERENCES prompt_template_versions(template_id, version_number)
+);
+
+-- Existing templates keep serving their current version as production
+INSERT INTO prompt_template_labels (template_id, label, version_number)
+SELECT t.id, 'production', v.version_number
+FROM prompt_templates t
+JOIN prompt_template_versions v ON v.template_id = t.id AND v.version = t.version
+ON CONFLICT (template_id, label) DO NOTHING;
@@ -0,0 +1,3 @@
+-- Chat templates store an ordered JSON array of role-tagged messages in content
+ALTER TABLE prompt_templates ADD COLUMN IF NOT EXISTS kind VARCHAR(20) DEFAULT 'text';
+ALTER TABLE prompt_template_versions ADD COLUMN IF NOT EXISTS kind VARCHAR(20) DEFAULT 'text';
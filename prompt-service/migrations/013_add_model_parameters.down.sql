@@ -0,0 +1,2 @@
+ALTER TABLE prompt_template_versions DROP COLUMN IF EXISTS parameters;
+ALTER TABLE prompt_templates DROP COLUMN IF EXISTS parameters;
@@ -0,0 +1,3 @@
+-- Model settings (model ID, temperature, max tokens, ...) versioned with the prompt
+ALTER TABLE prompt_templates ADD COLUMN IF NOT EXISTS parameters JSONB DEFAULT '{}';
+ALTER TABLE prompt_template_versions ADD COLUMN IF NOT EXISTS parameters JSONB DEFAULT '{}';
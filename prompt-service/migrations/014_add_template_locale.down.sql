@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_prompt_templates_name_locale;
+DELETE FROM prompt_templates WHERE locale <> 'vi';
+ALTER TABLE prompt_templates ADD CONSTRAINT prompt_templates_name_key UNIQUE (name);
+ALTER TABLE prompt_templates DROP COLUMN IF EXISTS locale;
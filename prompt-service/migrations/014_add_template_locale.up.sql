@@ -0,0 +1,4 @@
+-- Locale variants share a name; existing templates are the Vietnamese ones
+ALTER TABLE prompt_templates ADD COLUMN IF NOT EXISTS locale VARCHAR(20) NOT NULL DEFAULT 'vi';
+ALTER TABLE prompt_templates DROP CONSTRAINT IF EXISTS prompt_templates_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prompt_templates_name_locale ON prompt_templates(name, locale);
@@ -0,0 +1,2 @@
+ALTER TABLE prompt_template_versions DROP COLUMN IF EXISTS source_commit;
+ALTER TABLE prompt_template_versions DROP COLUMN IF EXISTS source_path;
@@ -0,0 +1,3 @@
+-- Where a version was synced from: a file in a git working tree and the commit
+ALTER TABLE prompt_template_versions ADD COLUMN IF NOT EXISTS source_path TEXT;
+ALTER TABLE prompt_template_versions ADD COLUMN IF NOT EXISTS source_commit VARCHAR(80);
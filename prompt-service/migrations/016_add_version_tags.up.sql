@@ -0,0 +1,2 @@
+-- Tags as they were when each version was recorded; NULL for older versions
+ALTER TABLE prompt_template_versions ADD COLUMN IF NOT EXISTS tags JSONB;
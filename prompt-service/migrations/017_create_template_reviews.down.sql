@@ -0,0 +1,3 @@
+UPDATE prompt_templates SET status = 'draft' WHERE status = 'review';
+ALTER TABLE prompt_templates ALTER COLUMN status SET DEFAULT 'active';
+DROP TABLE IF EXISTS prompt_template_reviews;
@@ -0,0 +1,24 @@
+-- Review history of templates: every status change and the version it applied to
+CREATE TABLE IF NOT EXISTS prompt_template_reviews (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    template_id UUID NOT NULL REFERENCES prompt_templates(id) ON DELETE CASCADE,
+    version_number INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    from_status VARCHAR(50) NOT NULL,
+    to_status VARCHAR(50) NOT NULL,
+    actor VARCHAR(255),
+    comment TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_template_reviews_template ON prompt_template_reviews(template_id, created_at);
+
+-- New templates start as drafts
+ALTER TABLE prompt_templates ALTER COLUMN status SET DEFAULT 'draft';
+
+-- Templates active before the workflow count as approved at their current version
+INSERT INTO prompt_template_reviews (template_id, version_number, action, from_status, to_status, actor, comment)
+SELECT t.id, v.version_number, 'approve', 'active', 'active', 'migration', 'active before the review workflow'
+FROM prompt_templates t
+JOIN prompt_template_versions v ON v.template_id = t.id AND v.version = t.version
+WHERE t.status = 'active';
@@ -0,0 +1,29 @@
+-- Append-only log of every change made through the prompt service
+CREATE TABLE IF NOT EXISTS prompt_audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    action VARCHAR(20) NOT NULL,
+    operation VARCHAR(255) NOT NULL,
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id VARCHAR(255) NOT NULL,
+    actor_id VARCHAR(255),
+    tenant_id VARCHAR(255),
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_resource ON prompt_audit_log(resource_type, resource_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON prompt_audit_log(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON prompt_audit_log(created_at);
+
+-- Entries can be added but never changed or removed
+CREATE OR REPLACE FUNCTION prompt_audit_log_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'prompt_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS prompt_audit_log_append_only ON prompt_audit_log;
+CREATE TRIGGER prompt_audit_log_append_only
+    BEFORE UPDATE OR DELETE ON prompt_audit_log
+    FOR EACH ROW EXECUTE FUNCTION prompt_audit_log_append_only();
@@ -0,0 +1,4 @@
+DELETE FROM prompt_templates WHERE tenant_id <> '';
+DROP INDEX IF EXISTS idx_prompt_templates_tenant_name_locale;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prompt_templates_name_locale ON prompt_templates(name, locale);
+ALTER TABLE prompt_templates DROP COLUMN IF EXISTS tenant_id;
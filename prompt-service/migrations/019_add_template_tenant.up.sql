@@ -0,0 +1,5 @@
+-- Templates belong to a tenant, or to no tenant ('') for the global ones
+-- tenants can override by name; existing templates are global
+ALTER TABLE prompt_templates ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(255) NOT NULL DEFAULT '';
+DROP INDEX IF EXISTS idx_prompt_templates_name_locale;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prompt_templates_tenant_name_locale ON prompt_templates(tenant_id, name, locale);
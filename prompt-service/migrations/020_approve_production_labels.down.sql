@@ -0,0 +1,2 @@
+DELETE FROM prompt_template_reviews
+WHERE actor = 'migration' AND comment = 'in production before approvals were enforced';
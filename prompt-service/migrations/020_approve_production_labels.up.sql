@@ -0,0 +1,16 @@
+-- Production only serves approved versions. Templates that were active before
+-- the review workflow (approved by 017) keep serving the version their
+-- production label points at; labels of drafts stay unapproved
+INSERT INTO prompt_template_reviews (template_id, version_number, action, from_status, to_status, actor, comment)
+SELECT t.id, l.version_number, 'approve', t.status, t.status, 'migration', 'in production before approvals were enforced'
+FROM prompt_template_labels l
+JOIN prompt_templates t ON t.id = l.template_id
+WHERE l.label = 'production'
+AND EXISTS (
+    SELECT 1 FROM prompt_template_reviews r
+    WHERE r.template_id = l.template_id AND r.actor = 'migration' AND r.comment = 'active before the review workflow'
+)
+AND NOT EXISTS (
+    SELECT 1 FROM prompt_template_reviews r
+    WHERE r.template_id = l.template_id AND r.version_number = l.version_number AND r.action = 'approve'
+);
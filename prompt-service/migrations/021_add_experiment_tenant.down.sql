@@ -0,0 +1,3 @@
+DELETE FROM prompt_experiments WHERE tenant_id <> '';
+DROP INDEX IF EXISTS idx_prompt_experiments_tenant_id;
+ALTER TABLE prompt_experiments DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,3 @@
+-- Experiments belong to a tenant like templates do; existing ones are global
+ALTER TABLE prompt_experiments ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(255) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_prompt_experiments_tenant_id ON prompt_experiments(tenant_id);
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/dto"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *auditRepository {
+	return &auditRepository{db: db}
+}
+
+// InTransaction runs fn in a transaction that every repository call made
+// with the context it is given joins, so a change and its audit event commit
+// together. It commits unless fn fails, and nests as a savepoint inside a
+// transaction ctx already carries.
+func (r *auditRepository) InTransaction(ctx context.Context, fn func(ctx context.Context) errors.BaseError) errors.BaseError {
+	var berr errors.BaseError
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if berr = fn(context.WithValue(ctx, txKey{}, tx)); berr != nil {
+			return berr
+		}
+		return nil
+	})
+	if berr != nil {
+		return berr
+	}
+	if err != nil {
+		return errors.Internal(err)
+	}
+	return nil
+}
+
+// RecordAuditEvent appends an event to the audit log
+func (r *auditRepository) RecordAuditEvent(ctx context.Context, event *entities.AuditEvent) errors.BaseError {
+	d := &dto.AuditEvent{
+		ID:           uuid.New(),
+		Action:       string(event.Action),
+		Operation:    event.Operation,
+		ResourceType: event.ResourceType,
+		ResourceID:   event.ResourceID,
+		ActorID:      event.ActorID,
+		TenantID:     event.TenantID,
+		Before:       rawJSON(event.Before),
+		After:        rawJSON(event.After),
+		CreatedAt:    time.Now(),
+	}
+	if err := conn(ctx, r.db).Create(d).Error; err != nil {
+		return errors.Internal(err)
+	}
+	event.ID, event.CreatedAt = d.ID.String(), d.CreatedAt
+	return nil
+}
+
+// ListAuditEvents lists audit events matching the filter, newest first
+func (r *auditRepository) ListAuditEvents(ctx context.Context, filter *entities.AuditFilter) ([]*entities.AuditEvent, int64, errors.BaseError) {
+	query := conn(ctx, r.db).Model(&dto.AuditEvent{})
+	if filter.ResourceType != "" {
+		query = query.Where("resource_type = ?", filter.ResourceType)
+	}
+	if filter.ResourceID != "" {
+		query = query.Where("resource_id = ?", filter.ResourceID)
+	}
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TenantID != "" {
+		query = query.Where("tenant_id = ?", filter.TenantID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", string(filter.Action))
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.Internal(err)
+	}
+
+	if filter.Page > 0 && filter.PageSize > 0 {
+		offset := (filter.Page - 1) * filter.PageSize
+		query = query.Offset(int(offset)).Limit(int(filter.PageSize))
+	}
+
+	var dtos []dto.AuditEvent
+	if err := query.Order("created_at DESC").Find(&dtos).Error; err != nil {
+		return nil, 0, errors.Internal(err)
+	}
+
+	results := make([]*entities.AuditEvent, 0, len(dtos))
+	for i := range dtos {
+		results = append(results, auditToEntity(&dtos[i]))
+	}
+	return results, total, nil
+}
+
+// rawJSON stores a snapshot, NULL when there is none
+func rawJSON(raw []byte) *string {
+	if len(raw) == 0 {
+		return nil
+	}
+	s := string(raw)
+	return &s
+}
+
+func auditToEntity(d *dto.AuditEvent) *entities.AuditEvent {
+	event := &entities.AuditEvent{
+		ID:           d.ID.String(),
+		Action:       entities.AuditAction(d.Action),
+		Operation:    d.Operation,
+		ResourceType: d.ResourceType,
+		ResourceID:   d.ResourceID,
+		ActorID:      d.ActorID,
+		TenantID:     d.TenantID,
+		CreatedAt:    d.CreatedAt,
+	}
+	if d.Before != nil {
+		event.Before = []byte(*d.Before)
+	}
+	if d.After != nil {
+		event.After = []byte(*d.After)
+	}
+	return event
+}
@@ -0,0 +1,455 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/dto"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type experimentRepository struct {
+	db *gorm.DB
+}
+
+func NewExperimentRepository(db *gorm.DB) *experimentRepository {
+	return &experimentRepository{db: db}
+}
+
+// CreateExperiment creates a new experiment
+func (r *experimentRepository) CreateExperiment(ctx context.Context, experiment *entities.PromptExperiment) (*entities.PromptExperiment, errors.BaseError) {
+	d, berr := experimentToDTO(experiment)
+	if berr != nil {
+		return nil, berr
+	}
+	d.ID = uuid.New()
+	d.TenantID = tenantOf(ctx)
+	d.CreatedAt = time.Now()
+	d.UpdatedAt = d.CreatedAt
+
+	if err := conn(ctx, r.db).Create(d).Error; err != nil {
+		return nil, errors.Internal(err)
+	}
+	return experimentToEntity(d), nil
+}
+
+// GetExperiment retrieves an experiment visible to the caller
+func (r *experimentRepository) GetExperiment(ctx context.Context, id string) (*entities.PromptExperiment, errors.BaseError) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.BadRequest("invalid id format")
+	}
+
+	var d dto.Experiment
+	query := tenantVisible(ctx, conn(ctx, r.db), "tenant_id")
+	if err := query.Where("id = ?", uid).First(&d).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NotFound("experiment not found")
+		}
+		return nil, errors.Internal(err)
+	}
+	return experimentToEntity(&d), nil
+}
+
+// ListExperiments lists the experiments visible to the caller
+func (r *experimentRepository) ListExperiments(ctx context.Context, filter *entities.ExperimentFilter) ([]*entities.PromptExperiment, int64, errors.BaseError) {
+	query := tenantVisible(ctx, conn(ctx, r.db).Model(&dto.Experiment{}), "tenant_id")
+	if filter.Status != "" {
+		query = query.Where("status = ?", string(filter.Status))
+	}
+
+	var total int64
+	query.Count(&total)
+
+	if filter.Page > 0 && filter.PageSize > 0 {
+		offset := (filter.Page - 1) * filter.PageSize
+		query = query.Offset(int(offset)).Limit(int(filter.PageSize))
+	}
+
+	var dtos []dto.Experiment
+	if err := query.Order("created_at DESC").Find(&dtos).Error; err != nil {
+		return nil, 0, errors.Internal(err)
+	}
+
+	results := make([]*entities.PromptExperiment, 0, len(dtos))
+	for i := range dtos {
+		results = append(results, experimentToEntity(&dtos[i]))
+	}
+	return results, total, nil
+}
+
+// UpdateExperiment stores the editable fields of an experiment as long as it
+// is still in one of the given states.
+func (r *experimentRepository) UpdateExperiment(ctx context.Context, experiment *entities.PromptExperiment, from []entities.ExperimentStatus) (*entities.PromptExperiment, errors.BaseError) {
+	d, berr := experimentToDTO(experiment)
+	if berr != nil {
+		return nil, berr
+	}
+
+	updates := map[string]interface{}{
+		"name":               d.Name,
+		"description":        d.Description,
+		"prompt_template_id": d.PromptTemplateID,
+		"model_id":           d.ModelID,
+		"config":             d.Config,
+		"variants":           d.Variants,
+		"holdout_percent":    d.HoldoutPercent,
+		"overrides":          d.Overrides,
+		"updated_at":         time.Now(),
+	}
+	if berr := r.updateWhereStatus(ctx, d.ID, from, updates); berr != nil {
+		return nil, berr
+	}
+	return r.GetExperiment(ctx, experiment.ID)
+}
+
+// TransitionExperiment moves an experiment to a new state. The update only
+// applies if the experiment is currently in one of the from states, so
+// concurrent transitions cannot both succeed.
+func (r *experimentRepository) TransitionExperiment(ctx context.Context, id string, from []entities.ExperimentStatus, to entities.ExperimentStatus, winner string) (*entities.PromptExperiment, errors.BaseError) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.BadRequest("invalid id format")
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":     string(to),
+		"updated_at": now,
+	}
+	switch to {
+	case entities.ExperimentStatusRunning:
+		updates["started_at"] = gorm.Expr("COALESCE(started_at, ?)", now)
+	case entities.ExperimentStatusCompleted:
+		updates["completed_at"] = now
+		updates["winner_variant"] = winner
+	}
+
+	if berr := r.updateWhereStatus(ctx, uid, from, updates); berr != nil {
+		return nil, berr
+	}
+	return r.GetExperiment(ctx, id)
+}
+
+// DeleteExperiment deletes an experiment of the caller that is not running
+func (r *experimentRepository) DeleteExperiment(ctx context.Context, id string) errors.BaseError {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return errors.BadRequest("invalid id format")
+	}
+
+	res := tenantOwned(ctx, conn(ctx, r.db)).
+		Where("id = ? AND status <> ?", uid, string(entities.ExperimentStatusRunning)).
+		Delete(&dto.Experiment{})
+	if res.Error != nil {
+		return errors.Internal(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		current, berr := r.ownedExperiment(ctx, id)
+		if berr != nil {
+			return berr
+		}
+		if current.Status == entities.ExperimentStatusRunning {
+			return errors.Conflict("a running experiment cannot be deleted, stop it first")
+		}
+		return errors.NotFound("experiment not found")
+	}
+	return nil
+}
+
+// GetAssignment returns the recorded assignment of a subject, if any
+func (r *experimentRepository) GetAssignment(ctx context.Context, experimentID string, subjectKey string) (*entities.ExperimentAssignment, errors.BaseError) {
+	uid, err := uuid.Parse(experimentID)
+	if err != nil {
+		return nil, errors.BadRequest("invalid id format")
+	}
+
+	var d dto.ExperimentAssignment
+	if err := conn(ctx, r.db).Where("experiment_id = ? AND subject_key = ?", uid, subjectKey).First(&d).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NotFound("assignment not found")
+		}
+		return nil, errors.Internal(err)
+	}
+	return assignmentToEntity(&d), nil
+}
+
+// SaveAssignment records the assignment of a subject. Unless overwrite is set
+// an existing assignment wins, which keeps concurrent first resolutions of the
+// same subject consistent.
+func (r *experimentRepository) SaveAssignment(ctx context.Context, assignment *entities.ExperimentAssignment, overwrite bool) (*entities.ExperimentAssignment, errors.BaseError) {
+	uid, err := uuid.Parse(assignment.ExperimentID)
+	if err != nil {
+		return nil, errors.BadRequest("invalid id format")
+	}
+
+	now := time.Now()
+	d := &dto.ExperimentAssignment{
+		ID:           uuid.New(),
+		ExperimentID: uid,
+		SubjectKey:   assignment.SubjectKey,
+		VariantKey:   assignment.VariantKey,
+		Holdout:      assignment.Holdout,
+		Forced:       assignment.Forced,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	onConflict := clause.OnConflict{
+		Columns:   []clause.Column{{Name: "experiment_id"}, {Name: "subject_key"}},
+		DoNothing: true,
+	}
+	if overwrite {
+		onConflict = clause.OnConflict{
+			Columns:   []clause.Column{{Name: "experiment_id"}, {Name: "subject_key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"variant_key", "holdout", "forced", "updated_at"}),
+		}
+	}
+
+	if err := conn(ctx, r.db).Clauses(onConflict).Create(d).Error; err != nil {
+		return nil, errors.Internal(err)
+	}
+	return r.GetAssignment(ctx, assignment.ExperimentID, assignment.SubjectKey)
+}
+
+// GetAssignmentByID retrieves an assignment
+func (r *experimentRepository) GetAssignmentByID(ctx context.Context, id string) (*entities.ExperimentAssignment, errors.BaseError) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.BadRequest("invalid assignment id format")
+	}
+
+	var d dto.ExperimentAssignment
+	if err := conn(ctx, r.db).Where("id = ?", uid).First(&d).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NotFound("assignment not found")
+		}
+		return nil, errors.Internal(err)
+	}
+	return assignmentToEntity(&d), nil
+}
+
+// CreateOutcome records an outcome for an assignment
+func (r *experimentRepository) CreateOutcome(ctx context.Context, outcome *entities.ExperimentOutcome, holdout bool) (*entities.ExperimentOutcome, errors.BaseError) {
+	experimentID, err := uuid.Parse(outcome.ExperimentID)
+	if err != nil {
+		return nil, errors.BadRequest("invalid id format")
+	}
+	assignmentID, err := uuid.Parse(outcome.AssignmentID)
+	if err != nil {
+		return nil, errors.BadRequest("invalid assignment id format")
+	}
+
+	d := &dto.ExperimentOutcome{
+		ID:           uuid.New(),
+		ExperimentID: experimentID,
+		AssignmentID: assignmentID,
+		VariantKey:   outcome.VariantKey,
+		Holdout:      holdout,
+		Metric:       outcome.Metric,
+		Kind:         string(outcome.Kind),
+		Value:        outcome.Value,
+		CreatedAt:    time.Now(),
+	}
+	if err := conn(ctx, r.db).Create(d).Error; err != nil {
+		return nil, errors.Internal(err)
+	}
+
+	outcome.ID = d.ID.String()
+	outcome.CreatedAt = d.CreatedAt
+	return outcome, nil
+}
+
+// AggregateOutcomes summarizes outcomes per metric, kind and variant, leaving
+// out subjects in the holdout.
+func (r *experimentRepository) AggregateOutcomes(ctx context.Context, experimentID string) ([]*entities.OutcomeAggregate, errors.BaseError) {
+	uid, err := uuid.Parse(experimentID)
+	if err != nil {
+		return nil, errors.BadRequest("invalid id format")
+	}
+
+	var rows []struct {
+		Metric     string
+		Kind       string
+		VariantKey string
+		Count      int64
+		Converted  int64
+		Sum        float64
+		Mean       float64
+		Variance   float64
+	}
+	err = conn(ctx, r.db).Model(&dto.ExperimentOutcome{}).
+		Select(`metric, kind, variant_key,
+			COUNT(*) AS count,
+			COUNT(DISTINCT assignment_id) FILTER (WHERE value > 0) AS converted,
+			COALESCE(SUM(value), 0) AS sum,
+			COALESCE(AVG(value), 0) AS mean,
+			COALESCE(VAR_SAMP(value), 0) AS variance`).
+		Where("experiment_id = ? AND holdout = ?", uid, false).
+		Group("metric, kind, variant_key").
+		Order("metric, kind, variant_key").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, errors.Internal(err)
+	}
+
+	results := make([]*entities.OutcomeAggregate, len(rows))
+	for i, row := range rows {
+		results[i] = &entities.OutcomeAggregate{
+			Metric:     row.Metric,
+			Kind:       entities.OutcomeKind(row.Kind),
+			VariantKey: row.VariantKey,
+			Count:      row.Count,
+			Converted:  row.Converted,
+			Sum:        row.Sum,
+			Mean:       row.Mean,
+			Variance:   row.Variance,
+		}
+	}
+	return results, nil
+}
+
+// CountExposures returns the number of assigned subjects per variant,
+// excluding the holdout.
+func (r *experimentRepository) CountExposures(ctx context.Context, experimentID string) (map[string]int64, errors.BaseError) {
+	uid, err := uuid.Parse(experimentID)
+	if err != nil {
+		return nil, errors.BadRequest("invalid id format")
+	}
+
+	var rows []struct {
+		VariantKey string
+		Count      int64
+	}
+	err = conn(ctx, r.db).Model(&dto.ExperimentAssignment{}).
+		Select("variant_key, COUNT(*) AS count").
+		Where("experiment_id = ? AND holdout = ?", uid, false).
+		Group("variant_key").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, errors.Internal(err)
+	}
+
+	exposures := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		exposures[row.VariantKey] = row.Count
+	}
+	return exposures, nil
+}
+
+func (r *experimentRepository) updateWhereStatus(ctx context.Context, id uuid.UUID, from []entities.ExperimentStatus, updates map[string]interface{}) errors.BaseError {
+	statuses := make([]string, len(from))
+	for i, s := range from {
+		statuses[i] = string(s)
+	}
+
+	res := tenantOwned(ctx, conn(ctx, r.db).Model(&dto.Experiment{})).
+		Where("id = ? AND status IN ?", id, statuses).
+		Updates(updates)
+	if res.Error != nil {
+		return errors.Internal(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		current, berr := r.ownedExperiment(ctx, id.String())
+		if berr != nil {
+			return berr
+		}
+		return errors.Conflict("experiment is " + string(current.Status))
+	}
+	return nil
+}
+
+// ownedExperiment explains why a scoped write matched nothing: the experiment
+// is missing, belongs to another tenant or is global, or is in another state.
+func (r *experimentRepository) ownedExperiment(ctx context.Context, id string) (*entities.PromptExperiment, errors.BaseError) {
+	current, berr := r.GetExperiment(ctx, id)
+	if berr != nil {
+		return nil, berr
+	}
+	if current.TenantID != tenantOf(ctx) {
+		return nil, errors.Forbidden("global experiments cannot be changed by a tenant")
+	}
+	return current, nil
+}
+
+func experimentToDTO(e *entities.PromptExperiment) (*dto.Experiment, errors.BaseError) {
+	templateID, err := uuid.Parse(e.PromptTemplateID)
+	if err != nil {
+		return nil, errors.BadRequest("invalid template id format")
+	}
+
+	d := &dto.Experiment{
+		Name:             e.Name,
+		Description:      e.Description,
+		PromptTemplateID: templateID,
+		ModelID:          e.ModelID,
+		HoldoutPercent:   e.HoldoutPercent,
+		TenantID:         e.TenantID,
+		Status:           string(e.Status),
+		WinnerVariant:    e.WinnerVariant,
+		CreatedAt:        e.CreatedAt,
+		UpdatedAt:        e.UpdatedAt,
+		StartedAt:        e.StartedAt,
+		CompletedAt:      e.CompletedAt,
+	}
+	if e.ID != "" {
+		if d.ID, err = uuid.Parse(e.ID); err != nil {
+			return nil, errors.BadRequest("invalid id format")
+		}
+	}
+
+	configJSON, _ := json.Marshal(e.Config)
+	variantsJSON, _ := json.Marshal(e.Variants)
+	overridesJSON, _ := json.Marshal(e.Overrides)
+	d.Config = string(configJSON)
+	d.Variants = string(variantsJSON)
+	d.Overrides = string(overridesJSON)
+	return d, nil
+}
+
+func experimentToEntity(d *dto.Experiment) *entities.PromptExperiment {
+	var config map[string]string
+	_ = json.Unmarshal([]byte(d.Config), &config)
+
+	var variants []entities.ExperimentVariant
+	_ = json.Unmarshal([]byte(d.Variants), &variants)
+
+	var overrides map[string]string
+	_ = json.Unmarshal([]byte(d.Overrides), &overrides)
+
+	return &entities.PromptExperiment{
+		ID:               d.ID.String(),
+		Name:             d.Name,
+		Description:      d.Description,
+		PromptTemplateID: d.PromptTemplateID.String(),
+		ModelID:          d.ModelID,
+		Config:           config,
+		Variants:         variants,
+		HoldoutPercent:   d.HoldoutPercent,
+		Overrides:        overrides,
+		TenantID:         d.TenantID,
+		Status:           entities.ExperimentStatus(d.Status),
+		WinnerVariant:    d.WinnerVariant,
+		CreatedAt:        d.CreatedAt,
+		UpdatedAt:        d.UpdatedAt,
+		StartedAt:        d.StartedAt,
+		CompletedAt:      d.CompletedAt,
+	}
+}
+
+func assignmentToEntity(d *dto.ExperimentAssignment) *entities.ExperimentAssignment {
+	return &entities.ExperimentAssignment{
+		ID:           d.ID.String(),
+		ExperimentID: d.ExperimentID.String(),
+		SubjectKey:   d.SubjectKey,
+		VariantKey:   d.VariantKey,
+		Holdout:      d.Holdout,
+		Forced:       d.Forced,
+		CreatedAt:    d.CreatedAt,
+	}
+}
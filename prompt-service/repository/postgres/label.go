@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/dto"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ListTemplateLabels returns the labels of a template ordered by name
+func (r *promptRepository) ListTemplateLabels(ctx context.Context, templateID string) ([]*entities.TemplateLabel, errors.BaseError) {
+	uid, err := uuid.Parse(templateID)
+	if err != nil {
+		return nil, errors.BadRequest("invalid id format")
+	}
+
+	var dtos []dto.TemplateLabel
+	if err := conn(ctx, r.db).Where("template_id = ?", uid).Order("label").Find(&dtos).Error; err != nil {
+		return nil, errors.Internal(err)
+	}
+
+	results := make([]*entities.TemplateLabel, 0, len(dtos))
+	for i := range dtos {
+		results = append(results, labelToEntity(&dtos[i]))
+	}
+	return results, nil
+}
+
+// GetTemplateLabel retrieves a single label of a template
+func (r *promptRepository) GetTemplateLabel(ctx context.Context, templateID string, label string) (*entities.TemplateLabel, errors.BaseError) {
+	uid, err := uuid.Parse(templateID)
+	if err != nil {
+		return nil, errors.BadRequest("invalid id format")
+	}
+
+	var d dto.TemplateLabel
+	if err := conn(ctx, r.db).Where("template_id = ? AND label = ?", uid, label).First(&d).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NotFound(fmt.Sprintf("label %s not found", label))
+		}
+		return nil, errors.Internal(err)
+	}
+	return labelToEntity(&d), nil
+}
+
+// SetTemplateLabel points a label at a version, creating the label if needed.
+// When from is set the label is only moved if it still points at that
+// version, so concurrent promotions cannot silently overwrite each other.
+func (r *promptRepository) SetTemplateLabel(ctx context.Context, templateID string, label string, number int, from int) (*entities.TemplateLabel, errors.BaseError) {
+	uid, err := uuid.Parse(templateID)
+	if err != nil {
+		return nil, errors.BadRequest("invalid id format")
+	}
+
+	var berr errors.BaseError
+	d := &dto.TemplateLabel{
+		TemplateID:    uid,
+		Label:         label,
+		VersionNumber: number,
+		UpdatedAt:     time.Now(),
+	}
+	err = conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&dto.PromptTemplateVersion{}).
+			Where("template_id = ? AND version_number = ?", uid, number).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			berr = errors.NotFound("template version not found")
+			return berr
+		}
+
+		if from > 0 {
+			var current dto.TemplateLabel
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("template_id = ? AND label = ?", uid, label).
+				First(&current).Error
+			if err != nil && err != gorm.ErrRecordNotFound {
+				return err
+			}
+			if err == gorm.ErrRecordNotFound || current.VersionNumber != from {
+				berr = errors.Conflict(fmt.Sprintf("label %s no longer points at %s", label, entities.FormatVersion(from)))
+				return berr
+			}
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "template_id"}, {Name: "label"}},
+			DoUpdates: clause.AssignmentColumns([]string{"version_number", "updated_at"}),
+		}).Create(d).Error
+	})
+	if berr != nil {
+		return nil, berr
+	}
+	if err != nil {
+		return nil, errors.Internal(err)
+	}
+	return labelToEntity(d), nil
+}
+
+// DeleteTemplateLabel removes a label from a template
+func (r *promptRepository) DeleteTemplateLabel(ctx context.Context, templateID string, label string) errors.BaseError {
+	uid, err := uuid.Parse(templateID)
+	if err != nil {
+		return errors.BadRequest("invalid id format")
+	}
+
+	result := conn(ctx, r.db).Where("template_id = ? AND label = ?", uid, label).Delete(&dto.TemplateLabel{})
+	if result.Error != nil {
+		return errors.Internal(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.NotFound(fmt.Sprintf("label %s not found", label))
+	}
+	return nil
+}
+
+func labelToEntity(d *dto.TemplateLabel) *entities.TemplateLabel {
+	return &entities.TemplateLabel{
+		TemplateID: d.TemplateID.String(),
+		Label:      d.Label,
+		Version:    entities.FormatVersion(d.VersionNumber),
+		UpdatedAt:  d.UpdatedAt,
+	}
+}
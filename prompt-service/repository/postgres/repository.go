@@ -3,6 +3,9 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/blcvn/backend/services/prompt-service/common/errors"
@@ -10,6 +13,7 @@ import (
 	"github.com/blcvn/backend/services/prompt-service/entities"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type promptRepository struct {
@@ -22,30 +26,55 @@ func NewPromptRepository(db *gorm.DB) *promptRepository {
 
 // CreateTemplate creates a new prompt template
 func (r *promptRepository) CreateTemplate(ctx context.Context, payload *entities.CreateTemplatePayload) (*entities.PromptTemplate, errors.BaseError) {
-	// Check existing name
+	// Check existing name; a tenant may reuse a global name to override it
+	tenant := tenantOf(ctx)
 	var count int64
-	r.db.Model(&dto.PromptTemplate{}).Where("name = ?", payload.Name).Count(&count)
+	conn(ctx, r.db).Model(&dto.PromptTemplate{}).Where("tenant_id = ? AND name = ? AND locale = ?", tenant, payload.Name, payload.Locale).Count(&count)
 	if count > 0 {
-		return nil, errors.Conflict("template with this name already exists")
+		return nil, errors.Conflict(fmt.Sprintf("template with this name already exists in locale %s", payload.Locale))
 	}
 
 	varsJSON, _ := json.Marshal(payload.Variables)
 	tagsJSON, _ := json.Marshal(payload.Tags)
 
+	now := time.Now()
 	dtoTemplate := &dto.PromptTemplate{
 		ID:          uuid.New(),
+		TenantID:    tenant,
 		Name:        payload.Name,
+		Locale:      payload.Locale,
 		Description: payload.Description,
-		Version:     "v1",
+		Version:     entities.FormatVersion(1),
 		Content:     payload.Content,
+		Syntax:      string(payload.Syntax),
+		Kind:        string(payload.Kind),
+		Parameters:  encodeParameters(payload.Parameters),
 		Variables:   string(varsJSON),
 		Tags:        string(tagsJSON),
-		Status:      string(entities.TemplateStatusActive),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Status:      string(entities.TemplateStatusDraft),
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
 
-	if err := r.db.WithContext(ctx).Create(dtoTemplate).Error; err != nil {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(dtoTemplate).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(newVersionRow(dtoTemplate, 1, payload.Source)).Error; err != nil {
+			return err
+		}
+		// Production starts at the first version, served once it is approved
+		if err := tx.Create(&dto.TemplateLabel{
+			TemplateID:    dtoTemplate.ID,
+			Label:         entities.LabelProduction,
+			VersionNumber: 1,
+			UpdatedAt:     now,
+		}).Error; err != nil {
+			return err
+		}
+		return replaceIncludes(tx, dtoTemplate.ID, payload.Includes)
+	})
+	if err != nil {
 		return nil, errors.Internal(err)
 	}
 
@@ -60,7 +89,8 @@ func (r *promptRepository) GetTemplate(ctx context.Context, id string) (*entitie
 	}
 
 	var dtoTemplate dto.PromptTemplate
-	if err := r.db.WithContext(ctx).Where("id = ?", uid).First(&dtoTemplate).Error; err != nil {
+	query := tenantVisible(ctx, conn(ctx, r.db), "tenant_id")
+	if err := query.Where("id = ?", uid).First(&dtoTemplate).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, errors.NotFound("template not found")
 		}
@@ -70,35 +100,58 @@ func (r *promptRepository) GetTemplate(ctx context.Context, id string) (*entitie
 	return r.dtoToEntity(&dtoTemplate)
 }
 
-// GetTemplateByName retrieves a template by name
-func (r *promptRepository) GetTemplateByName(ctx context.Context, name string) (*entities.PromptTemplate, errors.BaseError) {
-	var dtoTemplate dto.PromptTemplate
-	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&dtoTemplate).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, errors.NotFound("template not found")
+// GetTemplateByName retrieves the variant of a template in the first of
+// locales it exists in. In each locale the tenant's override of a global
+// template comes before the global one.
+func (r *promptRepository) GetTemplateByName(ctx context.Context, name string, locales []string) (*entities.PromptTemplate, errors.BaseError) {
+	var dtos []dto.PromptTemplate
+	query := tenantVisible(ctx, conn(ctx, r.db), "tenant_id")
+	if err := query.Where("name = ? AND locale IN ?", name, locales).Find(&dtos).Error; err != nil {
+		return nil, errors.Internal(err)
+	}
+	for _, locale := range locales {
+		for _, tenant := range []string{tenantOf(ctx), ""} {
+			for i := range dtos {
+				if dtos[i].Locale == locale && dtos[i].TenantID == tenant {
+					return r.dtoToEntity(&dtos[i])
+				}
+			}
 		}
+	}
+	return nil, errors.NotFound("template not found")
+}
+
+// ListTemplateLocales returns the locales each of the named templates has a
+// variant in
+func (r *promptRepository) ListTemplateLocales(ctx context.Context, names []string) (map[string][]string, errors.BaseError) {
+	var rows []struct {
+		Name   string
+		Locale string
+	}
+	query := tenantVisible(ctx, conn(ctx, r.db).Model(&dto.PromptTemplate{}), "tenant_id")
+	if err := query.
+		Select("DISTINCT name, locale").
+		Where("name IN ?", names).
+		Order("name, locale").
+		Find(&rows).Error; err != nil {
 		return nil, errors.Internal(err)
 	}
 
-	return r.dtoToEntity(&dtoTemplate)
+	locales := make(map[string][]string)
+	for _, row := range rows {
+		locales[row.Name] = append(locales[row.Name], row.Locale)
+	}
+	return locales, nil
 }
 
 // ListTemplates lists templates
 func (r *promptRepository) ListTemplates(ctx context.Context, filter *entities.TemplateFilter) ([]*entities.PromptTemplate, int64, errors.BaseError) {
-	query := r.db.WithContext(ctx).Model(&dto.PromptTemplate{})
-
-	if filter.Status != "" {
-		query = query.Where("status = ?", string(filter.Status))
-	}
-	// TODO: Implement tag filtering (requires JSONB query)
+	query := tenantVisible(ctx, conn(ctx, r.db).Model(&dto.PromptTemplate{}), "tenant_id")
+	query = filterTemplates(query, filter)
 
 	var total int64
 	query.Count(&total)
-
-	if filter.Page > 0 && filter.PageSize > 0 {
-		offset := (filter.Page - 1) * filter.PageSize
-		query = query.Offset(int(offset)).Limit(int(filter.PageSize))
-	}
+	query = paginate(query, filter)
 
 	var dtos []dto.PromptTemplate
 	if err := query.Order("created_at DESC").Find(&dtos).Error; err != nil {
@@ -114,6 +167,63 @@ func (r *promptRepository) ListTemplates(ctx context.Context, filter *entities.T
 	return results, total, nil
 }
 
+// SearchTemplates runs a full-text query against the search_vector column,
+// which weights the name above the description above the content. Results
+// are ordered by rank and carry a highlighted excerpt of the content.
+func (r *promptRepository) SearchTemplates(ctx context.Context, filter *entities.TemplateFilter) ([]*entities.TemplateSearchHit, int64, errors.BaseError) {
+	query := tenantVisible(ctx, conn(ctx, r.db).Model(&dto.PromptTemplate{}), "tenant_id")
+	query = filterTemplates(query, filter).
+		Where("search_vector @@ "+searchQuery, filter.Query)
+
+	var total int64
+	query.Count(&total)
+	query = paginate(query, filter)
+
+	var rows []struct {
+		dto.PromptTemplate
+		Rank    float64
+		Snippet string
+	}
+	err := query.
+		Select("prompt_templates.*, ts_rank_cd(search_vector, "+searchQuery+") AS rank, "+
+			"ts_headline('simple', content, "+searchQuery+", ?) AS snippet",
+			filter.Query, filter.Query, headlineOptions).
+		Order("rank DESC, updated_at DESC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, errors.Internal(err)
+	}
+
+	results := make([]*entities.TemplateSearchHit, 0, len(rows))
+	for i := range rows {
+		entity, _ := r.dtoToEntity(&rows[i].PromptTemplate)
+		results = append(results, &entities.TemplateSearchHit{
+			Template: entity,
+			Rank:     rows[i].Rank,
+			Snippet:  rows[i].Snippet,
+		})
+	}
+	return results, total, nil
+}
+
+// ListTags returns every tag in use with the number of templates carrying it,
+// most used first.
+func (r *promptRepository) ListTags(ctx context.Context) ([]*entities.TagCount, errors.BaseError) {
+	var results []*entities.TagCount
+	query := tenantVisible(ctx, conn(ctx, r.db), "prompt_templates.tenant_id")
+	err := query.
+		Table("prompt_templates, jsonb_array_elements_text(prompt_templates.tags) AS tag").
+		Select("tag, COUNT(*) AS count").
+		Where("jsonb_typeof(prompt_templates.tags) = 'array'").
+		Group("tag").
+		Order("count DESC, tag").
+		Scan(&results).Error
+	if err != nil {
+		return nil, errors.Internal(err)
+	}
+	return results, nil
+}
+
 // UpdateTemplate updates a template
 func (r *promptRepository) UpdateTemplate(ctx context.Context, payload *entities.UpdateTemplatePayload) (*entities.PromptTemplate, errors.BaseError) {
 	uid, err := uuid.Parse(payload.ID)
@@ -121,28 +231,176 @@ func (r *promptRepository) UpdateTemplate(ctx context.Context, payload *entities
 		return nil, errors.BadRequest("invalid id format")
 	}
 
-	updates := make(map[string]interface{})
-	if payload.Content != "" {
-		updates["content"] = payload.Content
+	var notFound bool
+	err = conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var current dto.PromptTemplate
+		if err := tenantOwned(ctx, tx.Clauses(clause.Locking{Strength: "UPDATE"})).Where("id = ?", uid).First(&current).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				notFound = true
+			}
+			return err
+		}
+
+		updates := make(map[string]interface{})
+		changed := false
+		if payload.Content != "" && payload.Content != current.Content {
+			current.Content = payload.Content
+			updates["content"] = payload.Content
+			changed = true
+		}
+		if payload.Syntax != "" && string(payload.Syntax) != current.Syntax {
+			current.Syntax = string(payload.Syntax)
+			updates["syntax"] = string(payload.Syntax)
+			changed = true
+		}
+		if payload.Kind != "" && string(payload.Kind) != current.Kind {
+			current.Kind = string(payload.Kind)
+			updates["kind"] = string(payload.Kind)
+			changed = true
+		}
+		if payload.Parameters != nil {
+			params := encodeParameters(payload.Parameters)
+			if !jsonEqual(params, current.Parameters) {
+				current.Parameters = params
+				updates["parameters"] = params
+				changed = true
+			}
+		}
+		if payload.Variables != nil {
+			varsJSON, _ := json.Marshal(payload.Variables)
+			if !jsonEqual(string(varsJSON), current.Variables) {
+				current.Variables = string(varsJSON)
+				updates["variables"] = string(varsJSON)
+				changed = true
+			}
+		}
+		if payload.Tags != nil {
+			var stored []string
+			_ = json.Unmarshal([]byte(current.Tags), &stored)
+			if !slices.Equal(stored, payload.Tags) {
+				tagsJSON, _ := json.Marshal(payload.Tags)
+				current.Tags = string(tagsJSON)
+				updates["tags"] = string(tagsJSON)
+				changed = true
+			}
+		}
+		if payload.Description != nil {
+			updates["description"] = *payload.Description
+		}
+
+		// Content, syntax, kind, parameters, variables and tags are versioned:
+		// every change appends an immutable revision and bumps the current
+		// version.
+		if changed {
+			var latest int
+			if err := tx.Model(&dto.PromptTemplateVersion{}).
+				Where("template_id = ?", uid).
+				Select("COALESCE(MAX(version_number), 0)").
+				Scan(&latest).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(newVersionRow(&current, latest+1, payload.Source)).Error; err != nil {
+				return err
+			}
+			updates["version"] = entities.FormatVersion(latest + 1)
+
+			// Only reviewed versions stay under review or active
+			if current.Status == string(entities.TemplateStatusReview) || current.Status == string(entities.TemplateStatusActive) {
+				if err := tx.Create(reviseRow(&current, latest+1)).Error; err != nil {
+					return err
+				}
+				updates["status"] = string(entities.TemplateStatusDraft)
+			}
+		}
+		if payload.Includes != nil {
+			if err := replaceIncludes(tx, uid, payload.Includes); err != nil {
+				return err
+			}
+		}
+		updates["updated_at"] = time.Now()
+
+		return tx.Model(&dto.PromptTemplate{}).Where("id = ?", uid).Updates(updates).Error
+	})
+	if err != nil {
+		if notFound {
+			return nil, errors.NotFound("template not found")
+		}
+		return nil, errors.Internal(err)
+	}
+
+	return r.GetTemplate(ctx, payload.ID)
+}
+
+// ListTemplateVersions returns every revision of a template, newest first
+func (r *promptRepository) ListTemplateVersions(ctx context.Context, templateID string) ([]*entities.PromptTemplateVersion, errors.BaseError) {
+	uid, err := uuid.Parse(templateID)
+	if err != nil {
+		return nil, errors.BadRequest("invalid id format")
+	}
+
+	var dtos []dto.PromptTemplateVersion
+	if err := conn(ctx, r.db).Where("template_id = ?", uid).Order("version_number DESC").Find(&dtos).Error; err != nil {
+		return nil, errors.Internal(err)
 	}
-	if payload.Status != "" {
-		updates["status"] = string(payload.Status)
+
+	results := make([]*entities.PromptTemplateVersion, 0, len(dtos))
+	for i := range dtos {
+		results = append(results, versionToEntity(&dtos[i]))
 	}
-	if payload.Variables != nil {
-		varsJSON, _ := json.Marshal(payload.Variables)
-		updates["variables"] = string(varsJSON)
+	return results, nil
+}
+
+// GetTemplateVersion retrieves a single revision of a template
+func (r *promptRepository) GetTemplateVersion(ctx context.Context, templateID string, number int) (*entities.PromptTemplateVersion, errors.BaseError) {
+	uid, err := uuid.Parse(templateID)
+	if err != nil {
+		return nil, errors.BadRequest("invalid id format")
 	}
-	if payload.Tags != nil {
-		tagsJSON, _ := json.Marshal(payload.Tags)
-		updates["tags"] = string(tagsJSON)
+
+	var d dto.PromptTemplateVersion
+	if err := conn(ctx, r.db).Where("template_id = ? AND version_number = ?", uid, number).First(&d).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NotFound("template version not found")
+		}
+		return nil, errors.Internal(err)
 	}
-	updates["updated_at"] = time.Now()
+	return versionToEntity(&d), nil
+}
 
-	if err := r.db.WithContext(ctx).Model(&dto.PromptTemplate{}).Where("id = ?", uid).Updates(updates).Error; err != nil {
+// ListIncludingTemplates returns the templates whose current content
+// includes the named partial, together with the reference they use.
+func (r *promptRepository) ListIncludingTemplates(ctx context.Context, partialName string) ([]*entities.TemplateDependent, errors.BaseError) {
+	var rows []struct {
+		ID             uuid.UUID
+		Name           string
+		Locale         string
+		Version        string
+		PartialVersion string
+	}
+	query := conn(ctx, r.db).Table("prompt_template_includes AS i").
+		Select("t.id, t.name, t.locale, t.version, i.partial_version").
+		Joins("JOIN prompt_templates t ON t.id = i.template_id").
+		Where("i.partial_name = ?", partialName)
+	err := tenantVisible(ctx, query, "t.tenant_id").
+		Order("t.name, t.locale, i.partial_version").
+		Scan(&rows).Error
+	if err != nil {
 		return nil, errors.Internal(err)
 	}
 
-	return r.GetTemplate(ctx, payload.ID)
+	results := make([]*entities.TemplateDependent, 0, len(rows))
+	for _, row := range rows {
+		include := entities.TemplateInclude{Name: partialName, Version: row.PartialVersion}
+		results = append(results, &entities.TemplateDependent{
+			ID:       row.ID.String(),
+			Name:     row.Name,
+			Locale:   row.Locale,
+			Version:  row.Version,
+			Includes: include.Ref(),
+			Pinned:   include.Version != "",
+		})
+	}
+	return results, nil
 }
 
 // DeleteTemplate deletes a template
@@ -152,7 +410,7 @@ func (r *promptRepository) DeleteTemplate(ctx context.Context, id string) errors
 		return errors.BadRequest("invalid id format")
 	}
 
-	if err := r.db.WithContext(ctx).Delete(&dto.PromptTemplate{}, "id = ?", uid).Error; err != nil {
+	if err := tenantOwned(ctx, conn(ctx, r.db)).Delete(&dto.PromptTemplate{}, "id = ?", uid).Error; err != nil {
 		return errors.Internal(err)
 	}
 	return nil
@@ -167,10 +425,16 @@ func (r *promptRepository) dtoToEntity(d *dto.PromptTemplate) (*entities.PromptT
 
 	return &entities.PromptTemplate{
 		ID:          d.ID.String(),
+		TenantID:    d.TenantID,
 		Name:        d.Name,
+		Locale:      d.Locale,
 		Description: d.Description,
 		Version:     d.Version,
 		Content:     d.Content,
+		Syntax:      entities.TemplateSyntax(d.Syntax),
+		Kind:        entities.TemplateKind(d.Kind),
+		Messages:    decodeMessages(d.Kind, d.Content),
+		Parameters:  decodeParameters(d.Parameters),
 		Variables:   vars,
 		Tags:        tags,
 		Status:      entities.TemplateStatus(d.Status),
@@ -178,3 +442,173 @@ func (r *promptRepository) dtoToEntity(d *dto.PromptTemplate) (*entities.PromptT
 		UpdatedAt:   d.UpdatedAt,
 	}, nil
 }
+
+func versionToEntity(d *dto.PromptTemplateVersion) *entities.PromptTemplateVersion {
+	var vars []entities.Variable
+	_ = json.Unmarshal([]byte(d.Variables), &vars)
+
+	return &entities.PromptTemplateVersion{
+		ID:         d.ID.String(),
+		TemplateID: d.TemplateID.String(),
+		Version:    d.Version,
+		Number:     d.VersionNumber,
+		Content:    d.Content,
+		Syntax:     entities.TemplateSyntax(d.Syntax),
+		Kind:       entities.TemplateKind(d.Kind),
+		Messages:   decodeMessages(d.Kind, d.Content),
+		Parameters: decodeParameters(d.Parameters),
+		Variables:  vars,
+		Tags:       decodeVersionTags(d.Tags),
+		Source:     versionSource(d),
+		CreatedAt:  d.CreatedAt,
+	}
+}
+
+// decodeVersionTags returns nil for versions recorded before tags were
+func decodeVersionTags(raw *string) []string {
+	if raw == nil {
+		return nil
+	}
+	tags := []string{}
+	_ = json.Unmarshal([]byte(*raw), &tags)
+	return tags
+}
+
+func versionSource(d *dto.PromptTemplateVersion) *entities.TemplateSource {
+	if d.SourcePath == nil {
+		return nil
+	}
+	source := &entities.TemplateSource{Path: *d.SourcePath}
+	if d.SourceCommit != nil {
+		source.Commit = *d.SourceCommit
+	}
+	return source
+}
+
+// newVersionRow snapshots the versioned fields of a template row
+func newVersionRow(t *dto.PromptTemplate, number int, source *entities.TemplateSource) *dto.PromptTemplateVersion {
+	row := &dto.PromptTemplateVersion{
+		ID:            uuid.New(),
+		TemplateID:    t.ID,
+		VersionNumber: number,
+		Version:       entities.FormatVersion(number),
+		Content:       t.Content,
+		Syntax:        t.Syntax,
+		Kind:          t.Kind,
+		Parameters:    t.Parameters,
+		Variables:     t.Variables,
+		Tags:          &t.Tags,
+		CreatedAt:     time.Now(),
+	}
+	if source != nil {
+		row.SourcePath, row.SourceCommit = &source.Path, &source.Commit
+	}
+	return row
+}
+
+const (
+	// searchQuery parses user input with web search syntax: quoted phrases,
+	// "or" and -exclusions. The simple configuration does no stemming, which
+	// suits the mix of Vietnamese and English prompts.
+	searchQuery = "websearch_to_tsquery('simple', ?)"
+	// headlineOptions configures the snippets returned by ts_headline
+	headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=\" ... \""
+)
+
+// filterTemplates applies the status and tag filters shared by listing and search
+func filterTemplates(query *gorm.DB, filter *entities.TemplateFilter) *gorm.DB {
+	if filter.Status != "" {
+		query = query.Where("status = ?", string(filter.Status))
+	}
+	if filter.Locale != "" {
+		query = query.Where("locale = ?", filter.Locale)
+	}
+	return whereTags(query, filter.Tags, filter.TagMode)
+}
+
+func paginate(query *gorm.DB, filter *entities.TemplateFilter) *gorm.DB {
+	if filter.Page > 0 && filter.PageSize > 0 {
+		offset := (filter.Page - 1) * filter.PageSize
+		query = query.Offset(int(offset)).Limit(int(filter.PageSize))
+	}
+	return query
+}
+
+// whereTags filters on the tags array with JSONB containment, which is served
+// by the GIN index on prompt_templates.tags.
+func whereTags(query *gorm.DB, tags []string, mode entities.TagMatchMode) *gorm.DB {
+	if len(tags) == 0 {
+		return query
+	}
+	if mode != entities.TagMatchAny {
+		tagsJSON, _ := json.Marshal(tags)
+		return query.Where("tags @> ?::jsonb", string(tagsJSON))
+	}
+
+	conditions := make([]string, len(tags))
+	args := make([]interface{}, len(tags))
+	for i, tag := range tags {
+		tagJSON, _ := json.Marshal([]string{tag})
+		conditions[i] = "tags @> ?::jsonb"
+		args[i] = string(tagJSON)
+	}
+	return query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+}
+
+// replaceIncludes records the partials referenced by a template's content
+func replaceIncludes(tx *gorm.DB, templateID uuid.UUID, includes []entities.TemplateInclude) error {
+	if err := tx.Where("template_id = ?", templateID).Delete(&dto.TemplateInclude{}).Error; err != nil {
+		return err
+	}
+	if len(includes) == 0 {
+		return nil
+	}
+	rows := make([]dto.TemplateInclude, len(includes))
+	for i, include := range includes {
+		rows[i] = dto.TemplateInclude{
+			TemplateID:     templateID,
+			PartialName:    include.Name,
+			PartialVersion: include.Version,
+		}
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+// decodeMessages returns the messages of a chat template, nil for text ones
+func decodeMessages(kind string, content string) []entities.TemplateMessage {
+	if entities.TemplateKind(kind) != entities.TemplateKindChat {
+		return nil
+	}
+	messages, _ := entities.ParseMessages(content)
+	return messages
+}
+
+// encodeParameters returns the stored form of model parameters
+func encodeParameters(params *entities.ModelParameters) string {
+	if params.IsEmpty() {
+		return "{}"
+	}
+	b, _ := json.Marshal(params)
+	return string(b)
+}
+
+// decodeParameters returns nil when no parameters are stored
+func decodeParameters(raw string) *entities.ModelParameters {
+	var params entities.ModelParameters
+	if json.Unmarshal([]byte(raw), &params) != nil || params.IsEmpty() {
+		return nil
+	}
+	return &params
+}
+
+// jsonEqual compares two JSON documents semantically, ignoring whitespace
+// and key order differences introduced by the jsonb column.
+func jsonEqual(a, b string) bool {
+	var va, vb interface{}
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return a == b
+	}
+	ca, _ := json.Marshal(va)
+	cb, _ := json.Marshal(vb)
+	return string(ca) == string(cb)
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/dto"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReviewTemplate moves a template to review.To and records the change. The
+// move only happens while the template is in one of the from statuses and
+// still at review.Version, so a review never applies to content the reviewer
+// has not seen.
+func (r *promptRepository) ReviewTemplate(ctx context.Context, review *entities.TemplateReview, from []entities.TemplateStatus) (*entities.PromptTemplate, errors.BaseError) {
+	uid, err := uuid.Parse(review.TemplateID)
+	if err != nil {
+		return nil, errors.BadRequest("invalid id format")
+	}
+	number, ok := entities.ParseVersion(review.Version)
+	if !ok {
+		return nil, errors.BadRequest(fmt.Sprintf("invalid version: %s", review.Version))
+	}
+
+	var berr errors.BaseError
+	err = conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var current dto.PromptTemplate
+		if err := tenantOwned(ctx, tx.Clauses(clause.Locking{Strength: "UPDATE"})).Where("id = ?", uid).First(&current).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				berr = errors.NotFound("template not found")
+			}
+			return err
+		}
+		if !hasStatus(from, current.Status) {
+			berr = errors.Conflict(fmt.Sprintf("template is %s", current.Status))
+			return berr
+		}
+		if current.Version != review.Version {
+			berr = errors.Conflict(fmt.Sprintf("template changed to %s", current.Version))
+			return berr
+		}
+
+		if err := tx.Model(&dto.PromptTemplate{}).Where("id = ?", uid).Updates(map[string]interface{}{
+			"status":     string(review.To),
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&dto.TemplateReview{
+			ID:            uuid.New(),
+			TemplateID:    uid,
+			VersionNumber: number,
+			Action:        string(review.Action),
+			FromStatus:    current.Status,
+			ToStatus:      string(review.To),
+			Actor:         review.Actor,
+			Comment:       review.Comment,
+			CreatedAt:     time.Now(),
+		}).Error
+	})
+	if berr != nil {
+		return nil, berr
+	}
+	if err != nil {
+		return nil, errors.Internal(err)
+	}
+	return r.GetTemplate(ctx, review.TemplateID)
+}
+
+// ListTemplateReviews returns the review history of a template, newest first
+func (r *promptRepository) ListTemplateReviews(ctx context.Context, templateID string) ([]*entities.TemplateReview, errors.BaseError) {
+	uid, err := uuid.Parse(templateID)
+	if err != nil {
+		return nil, errors.BadRequest("invalid id format")
+	}
+
+	var dtos []dto.TemplateReview
+	if err := conn(ctx, r.db).Where("template_id = ?", uid).Order("created_at DESC").Find(&dtos).Error; err != nil {
+		return nil, errors.Internal(err)
+	}
+
+	results := make([]*entities.TemplateReview, 0, len(dtos))
+	for i := range dtos {
+		results = append(results, reviewToEntity(&dtos[i]))
+	}
+	return results, nil
+}
+
+// reviseRow records a new version sending a template back to draft
+func reviseRow(t *dto.PromptTemplate, number int) *dto.TemplateReview {
+	return &dto.TemplateReview{
+		ID:            uuid.New(),
+		TemplateID:    t.ID,
+		VersionNumber: number,
+		Action:        string(entities.ReviewRevise),
+		FromStatus:    t.Status,
+		ToStatus:      string(entities.TemplateStatusDraft),
+		CreatedAt:     time.Now(),
+	}
+}
+
+func hasStatus(statuses []entities.TemplateStatus, status string) bool {
+	for _, s := range statuses {
+		if string(s) == status {
+			return true
+		}
+	}
+	return false
+}
+
+func reviewToEntity(d *dto.TemplateReview) *entities.TemplateReview {
+	return &entities.TemplateReview{
+		ID:         d.ID.String(),
+		TemplateID: d.TemplateID.String(),
+		Version:    entities.FormatVersion(d.VersionNumber),
+		Action:     entities.TemplateReviewAction(d.Action),
+		From:       entities.TemplateStatus(d.FromStatus),
+		To:         entities.TemplateStatus(d.ToStatus),
+		Actor:      d.Actor,
+		Comment:    d.Comment,
+		CreatedAt:  d.CreatedAt,
+	}
+}
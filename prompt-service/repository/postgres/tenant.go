@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"gorm.io/gorm"
+)
+
+// tenantOf returns the tenant of the request in ctx, "" for callers outside
+// any tenant, which only see global templates and experiments
+func tenantOf(ctx context.Context) string {
+	return entities.RequestFromContext(ctx).Actor.TenantID
+}
+
+// tenantVisible restricts a query of templates or experiments to the global
+// rows and those of the tenant in ctx. column names the tenant_id column.
+func tenantVisible(ctx context.Context, query *gorm.DB, column string) *gorm.DB {
+	tenant := tenantOf(ctx)
+	if tenant == "" {
+		return query.Where(column + " = ''")
+	}
+	return query.Where(column+" IN ?", []string{"", tenant})
+}
+
+// tenantOwned restricts a query of templates or experiments to the rows the
+// caller may change: its tenant's, or the global ones for callers outside any
+// tenant
+func tenantOwned(ctx context.Context, query *gorm.DB) *gorm.DB {
+	return query.Where("tenant_id = ?", tenantOf(ctx))
+}
@@ -0,0 +1,19 @@
+package postgres
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// txKey carries the transaction started by InTransaction in a context
+type txKey struct{}
+
+// conn returns the handle queries for ctx go through: the transaction ctx
+// carries, if any, so every repository joins it
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
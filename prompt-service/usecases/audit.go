@@ -0,0 +1,102 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+)
+
+type iAuditRepository interface {
+	InTransaction(ctx context.Context, fn func(ctx context.Context) errors.BaseError) errors.BaseError
+	RecordAuditEvent(ctx context.Context, event *entities.AuditEvent) errors.BaseError
+	ListAuditEvents(ctx context.Context, filter *entities.AuditFilter) ([]*entities.AuditEvent, int64, errors.BaseError)
+}
+
+const (
+	// defaultAuditPageSize applies when listing audit events without a page size
+	defaultAuditPageSize = 50
+	// maxAuditPageSize bounds a page of audit events
+	maxAuditPageSize = 500
+)
+
+// auditUsecase keeps the audit log. A nil auditUsecase records nothing, for
+// tools that run without one.
+type auditUsecase struct {
+	repo iAuditRepository
+}
+
+func NewAuditUsecase(repo iAuditRepository) *auditUsecase {
+	return &auditUsecase{repo: repo}
+}
+
+// ListAuditEvents lists audit events, newest first, a page at a time. A
+// tenant only sees the events of its own requests.
+func (u *auditUsecase) ListAuditEvents(ctx context.Context, filter *entities.AuditFilter) ([]*entities.AuditEvent, int64, errors.BaseError) {
+	if tenant := entities.RequestFromContext(ctx).Actor.TenantID; tenant != "" {
+		if filter.TenantID != "" && filter.TenantID != tenant {
+			return nil, 0, errors.Forbidden("cannot list another tenant's audit events")
+		}
+		filter.TenantID = tenant
+	}
+	if filter.Since != nil && filter.Until != nil && !filter.Since.Before(*filter.Until) {
+		return nil, 0, errors.BadRequest("since must be before until")
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = defaultAuditPageSize
+	} else if filter.PageSize > maxAuditPageSize {
+		filter.PageSize = maxAuditPageSize
+	}
+	return u.repo.ListAuditEvents(ctx, filter)
+}
+
+// audited makes a change and records its events in one transaction, so no
+// change is kept without its audit event. change must record through the
+// context it is given.
+func (u *auditUsecase) audited(ctx context.Context, change func(ctx context.Context) errors.BaseError) errors.BaseError {
+	if u == nil {
+		return change(ctx)
+	}
+	return u.repo.InTransaction(ctx, change)
+}
+
+// record appends an event for a change made within audited, attributed to
+// the request in ctx. Before is nil for creates and after is nil for deletes.
+// A failure rolls the change back.
+func (u *auditUsecase) record(ctx context.Context, action entities.AuditAction, resourceType string, resourceID string, before interface{}, after interface{}) errors.BaseError {
+	if u == nil {
+		return nil
+	}
+	request := entities.RequestFromContext(ctx)
+	event := &entities.AuditEvent{
+		Action:       action,
+		Operation:    request.Operation,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		ActorID:      request.Actor.ID,
+		TenantID:     request.Actor.TenantID,
+		Before:       snapshot(before),
+		After:        snapshot(after),
+	}
+	if event.Operation == "" {
+		event.Operation = "unknown"
+	}
+	return u.repo.RecordAuditEvent(ctx, event)
+}
+
+// snapshot encodes a resource as it is stored in the audit log; nil and nil
+// pointers have no snapshot
+func snapshot(resource interface{}) json.RawMessage {
+	if resource == nil {
+		return nil
+	}
+	data, err := json.Marshal(resource)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return data
+}
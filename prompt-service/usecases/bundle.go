@@ -0,0 +1,275 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/blcvn/backend/services/prompt-service/helper"
+)
+
+// ImportTemplates applies a prompt bundle. Templates missing from the store
+// are created, changed ones are updated, which records a new version, and
+// unchanged ones are left alone, so importing the same bundle twice changes
+// nothing. Partials are applied before the templates including them. On a dry
+// run nothing is written; either way each change carries a diff from the
+// stored template to the file.
+func (u *promptUsecase) ImportTemplates(ctx context.Context, files []*entities.PromptFile, dryRun bool) (*entities.ImportReport, errors.BaseError) {
+	seen := make(map[string]string, len(files))
+	for _, file := range files {
+		if err := u.normalizePromptFile(file); err != nil {
+			return nil, errors.NewBaseError(err.GetCode(), fmt.Errorf("%s: %w", file.Path, err))
+		}
+		key := file.Name + "@" + file.Locale
+		if other, ok := seen[key]; ok {
+			return nil, errors.BadRequest(fmt.Sprintf("%s: %s (%s) is also defined in %s", file.Path, file.Name, file.Locale, other))
+		}
+		seen[key] = file.Path
+	}
+
+	report := &entities.ImportReport{DryRun: dryRun, Changes: []entities.ImportChange{}}
+	for _, file := range u.orderBundle(files) {
+		change, err := u.importTemplate(ctx, file, dryRun)
+		if err != nil {
+			return nil, errors.NewBaseError(err.GetCode(), fmt.Errorf("%s: %w", file.Path, err))
+		}
+		report.Changes = append(report.Changes, *change)
+	}
+	return report, nil
+}
+
+// ExportTemplates returns every template the caller owns as a prompt file,
+// named for the given bundle format. A tenant's bundle holds its overrides,
+// not the global templates they fall back to.
+func (u *promptUsecase) ExportTemplates(ctx context.Context, format string) ([]*entities.PromptFile, errors.BaseError) {
+	if format != helper.BundleFormatYAML && format != helper.BundleFormatMarkdown {
+		return nil, errors.BadRequest(fmt.Sprintf("unsupported bundle format: %s", format))
+	}
+	templates, _, err := u.repo.ListTemplates(ctx, &entities.TemplateFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := entities.RequestFromContext(ctx).Actor.TenantID
+	files := make([]*entities.PromptFile, 0, len(templates))
+	for _, t := range templates {
+		if t.TenantID != tenant {
+			continue
+		}
+		file := templateFile(t)
+		file.Path = helper.PromptFileName(file, format, u.locales.Default)
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+func (u *promptUsecase) importTemplate(ctx context.Context, file *entities.PromptFile, dryRun bool) (*entities.ImportChange, errors.BaseError) {
+	change := &entities.ImportChange{Path: file.Path, Name: file.Name, Locale: file.Locale}
+
+	current, err := u.repo.GetTemplateByName(ctx, file.Name, []string{file.Locale})
+	if err != nil && err.GetCode() != errors.NOT_FOUND {
+		return nil, err
+	}
+	// A tenant importing a global template's name overrides it
+	if current != nil && current.TenantID != entities.RequestFromContext(ctx).Actor.TenantID {
+		current = nil
+	}
+	if current == nil {
+		change.Action = entities.ImportCreate
+		change.Diff = helper.UnifiedDiff("", encodeFile(file), "/dev/null", file.Path)
+		if dryRun {
+			return change, nil
+		}
+		created, err := u.CreateTemplate(ctx, &entities.CreateTemplatePayload{
+			Name:        file.Name,
+			Locale:      file.Locale,
+			Description: file.Description,
+			Content:     file.Content,
+			Syntax:      file.Syntax,
+			Kind:        file.Kind,
+			Messages:    file.Messages,
+			Parameters:  file.Parameters,
+			Variables:   file.Variables,
+			Tags:        file.Tags,
+			Source:      file.Source,
+		})
+		if err != nil {
+			return nil, err
+		}
+		change.Version = created.Version
+		return change, nil
+	}
+
+	stored := templateFile(current)
+	stored.Path = file.Path
+	desired := *file
+	desired.Variables = u.importedVariables(ctx, file, current)
+	change.Diff = helper.UnifiedDiff(encodeFile(stored), encodeFile(&desired), "stored/"+file.Path, file.Path)
+	if change.Diff == "" {
+		change.Action = entities.ImportUnchanged
+		change.Version = current.Version
+		return change, nil
+	}
+
+	change.Action = entities.ImportUpdate
+	if dryRun {
+		return change, nil
+	}
+	// The file is the whole template, so fields it leaves out are cleared
+	parameters := file.Parameters
+	if parameters == nil {
+		parameters = &entities.ModelParameters{}
+	}
+	tags := file.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	var variables []entities.Variable
+	if len(file.Variables) > 0 {
+		variables = file.Variables
+	}
+	updated, err := u.UpdateTemplate(ctx, &entities.UpdateTemplatePayload{
+		ID:          current.ID,
+		Content:     file.Content,
+		Syntax:      file.Syntax,
+		Kind:        file.Kind,
+		Messages:    file.Messages,
+		Parameters:  parameters,
+		Variables:   variables,
+		Tags:        tags,
+		Description: &file.Description,
+		Source:      file.Source,
+	})
+	if err != nil {
+		return nil, err
+	}
+	change.Version = updated.Version
+	return change, nil
+}
+
+// importedVariables predicts the variables UpdateTemplate saves for a file:
+// its declarations, or else the stored ones, merged with those inferred from
+// the content. Inference needs the partials, which on a dry run may only
+// exist in the bundle; the declarations are used as they are then.
+func (u *promptUsecase) importedVariables(ctx context.Context, file *entities.PromptFile, current *entities.PromptTemplate) []entities.Variable {
+	declared := file.Variables
+	if len(declared) == 0 {
+		declared = current.Variables
+	}
+	kind := file.Kind
+	content, err := chatContent(&kind, file.Content, file.Messages)
+	if err != nil {
+		return declared
+	}
+	inferred, err := u.inferVariables(ctx, file.Name, file.Locale, content, kind, file.Syntax)
+	if err != nil {
+		return declared
+	}
+	return helper.MergeVariables(helper.DeclaredVariables(declared), inferred)
+}
+
+// normalizePromptFile fills in the defaults a template gets when saved, so a
+// file matches the stored template it was exported from
+func (u *promptUsecase) normalizePromptFile(file *entities.PromptFile) errors.BaseError {
+	if file.Name == "" {
+		return errors.BadRequest("name is required")
+	}
+	locale, err := u.templateLocale(file.Locale)
+	if err != nil {
+		return err
+	}
+	file.Locale = locale
+	if file.Kind == "" {
+		file.Kind = entities.TemplateKindText
+		if len(file.Messages) > 0 {
+			file.Kind = entities.TemplateKindChat
+		}
+	}
+	if file.Syntax == "" {
+		file.Syntax = entities.TemplateSyntaxGo
+	}
+	if file.Parameters.IsEmpty() {
+		file.Parameters = nil
+	}
+	tags, err := normalizeTags(file.Tags)
+	if err != nil {
+		return err
+	}
+	file.Tags = nil
+	if len(tags) > 0 {
+		file.Tags = tags
+	}
+	return nil
+}
+
+// orderBundle sorts files so that partials come before the templates that
+// include them. Includes of templates outside the bundle are ignored, and
+// cycles are left for CreateTemplate to reject.
+func (u *promptUsecase) orderBundle(files []*entities.PromptFile) []*entities.PromptFile {
+	byName := make(map[string][]*entities.PromptFile)
+	for _, file := range files {
+		byName[file.Name] = append(byName[file.Name], file)
+	}
+
+	ordered := make([]*entities.PromptFile, 0, len(files))
+	visited := make(map[*entities.PromptFile]bool, len(files))
+	var visit func(file *entities.PromptFile)
+	visit = func(file *entities.PromptFile) {
+		if visited[file] {
+			return
+		}
+		visited[file] = true
+		kind := file.Kind
+		if content, err := chatContent(&kind, file.Content, file.Messages); err == nil {
+			refs, _ := u.extractIncludes(content, kind, file.Syntax)
+			for _, ref := range refs {
+				if include, ok := entities.ParseInclude(ref); ok {
+					for _, partial := range byName[include.Name] {
+						visit(partial)
+					}
+				}
+			}
+		}
+		ordered = append(ordered, file)
+	}
+	for _, file := range files {
+		visit(file)
+	}
+	return ordered
+}
+
+// templateFile converts a stored template to a prompt file
+func templateFile(t *entities.PromptTemplate) *entities.PromptFile {
+	file := &entities.PromptFile{
+		Name:        t.Name,
+		Locale:      t.Locale,
+		Description: t.Description,
+		Kind:        currentKind(t.Kind),
+		Syntax:      t.Syntax,
+		Variables:   t.Variables,
+	}
+	if file.Syntax == "" {
+		file.Syntax = entities.TemplateSyntaxGo
+	}
+	if !t.Parameters.IsEmpty() {
+		file.Parameters = t.Parameters
+	}
+	if len(t.Tags) > 0 {
+		file.Tags = t.Tags
+	}
+	if file.Kind == entities.TemplateKindChat {
+		file.Messages = t.Messages
+	} else {
+		file.Content = t.Content
+	}
+	return file
+}
+
+// encodeFile renders a prompt file as YAML for diffing
+func encodeFile(file *entities.PromptFile) string {
+	data, _ := helper.EncodePromptFile(file, helper.BundleFormatYAML)
+	return string(data)
+}
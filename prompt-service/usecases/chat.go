@@ -0,0 +1,134 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/blcvn/backend/services/prompt-service/helper"
+)
+
+// chatContent folds the messages of a chat template into the content that is
+// stored and versioned. Messages take precedence over content; chat content
+// supplied as JSON is decoded and re-encoded so it is stored canonically.
+// Text content is returned unchanged.
+func chatContent(kind *entities.TemplateKind, content string, messages []entities.TemplateMessage) (string, errors.BaseError) {
+	switch {
+	case len(messages) > 0:
+		if *kind != "" && *kind != entities.TemplateKindChat {
+			return "", errors.BadRequest("messages are only supported by chat templates")
+		}
+		*kind = entities.TemplateKindChat
+	case *kind == entities.TemplateKindChat && content != "":
+		parsed, err := entities.ParseMessages(content)
+		if err != nil {
+			return "", errors.BadRequest(err.Error())
+		}
+		messages = parsed
+	default:
+		return content, nil
+	}
+
+	if errs := entities.ValidateMessages(messages); len(errs) > 0 {
+		return "", errors.BadRequest("invalid messages: " + strings.Join(errs, "; "))
+	}
+	return entities.EncodeMessages(messages), nil
+}
+
+// segment is a piece of template content rendered on its own: the whole
+// content of a text template, or one message of a chat template.
+type segment struct {
+	label   string // prefix for errors, empty for text templates
+	content string
+}
+
+func templateSegments(content string, kind entities.TemplateKind) ([]segment, error) {
+	if kind != entities.TemplateKindChat {
+		return []segment{{content: content}}, nil
+	}
+	messages, err := entities.ParseMessages(content)
+	if err != nil {
+		return nil, err
+	}
+	segments := make([]segment, len(messages))
+	for i, m := range messages {
+		segments[i] = segment{label: fmt.Sprintf("message %d: ", i+1), content: m.Content}
+	}
+	return segments, nil
+}
+
+// extractIncludes returns the distinct partial references of every segment
+func (u *promptUsecase) extractIncludes(content string, kind entities.TemplateKind, syntax entities.TemplateSyntax) ([]string, error) {
+	segments, err := templateSegments(content, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []string
+	seen := make(map[string]bool)
+	for _, s := range segments {
+		found, err := u.engine.ExtractIncludes(s.content, syntax)
+		if err != nil {
+			return nil, fmt.Errorf("%s%w", s.label, err)
+		}
+		for _, ref := range found {
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs, nil
+}
+
+// renderMessages renders each message of a chat template with the same
+// values and partials.
+func (u *promptUsecase) renderMessages(content string, syntax entities.TemplateSyntax, values map[string]interface{}, partials map[string]*helper.Partial) ([]entities.RenderedMessage, error) {
+	messages, err := entities.ParseMessages(content)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := make([]entities.RenderedMessage, len(messages))
+	size := 0
+	for i, m := range messages {
+		text, err := u.engine.RenderWithPartials(m.Content, syntax, values, partials)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i+1, err)
+		}
+		if size += len(text); size > helper.MaxRenderedSize {
+			return nil, fmt.Errorf("rendered prompt exceeds %d bytes", helper.MaxRenderedSize)
+		}
+		rendered[i] = entities.RenderedMessage{Role: m.Role, Name: m.Name, Content: text, Example: m.Example}
+	}
+	return rendered, nil
+}
+
+// RenderProviderRequest renders a template like RenderTemplateWithOptions and
+// shapes the result into the request body of an LLM provider.
+func (u *promptUsecase) RenderProviderRequest(ctx context.Context, name string, variables map[string]string, provider entities.Provider, opts *entities.RenderOptions) (*entities.RenderedPrompt, interface{}, errors.BaseError) {
+	if !provider.IsValid() {
+		return nil, nil, errors.BadRequest(fmt.Sprintf("unsupported provider: %s", provider))
+	}
+
+	rendered, err := u.RenderTemplateWithOptions(ctx, name, variables, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	request, buildErr := helper.ProviderRequest(provider, renderedMessages(rendered), rendered.Parameters)
+	if buildErr != nil {
+		return nil, nil, errors.BadRequest(buildErr.Error())
+	}
+	return rendered, request, nil
+}
+
+// renderedMessages returns the messages of a rendered prompt, treating the
+// text of a text template as a single user message.
+func renderedMessages(rendered *entities.RenderedPrompt) []entities.RenderedMessage {
+	if rendered.Messages != nil {
+		return rendered.Messages
+	}
+	return []entities.RenderedMessage{{Role: entities.RoleUser, Content: rendered.Content}}
+}
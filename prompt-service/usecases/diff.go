@@ -0,0 +1,181 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/blcvn/backend/services/prompt-service/helper"
+)
+
+// DiffTemplateVersions compares two revisions of a template. Either side may
+// be a version ("v3"), a label ("production") or "latest". Without to the
+// latest content is compared; without from, the version before to.
+func (u *promptUsecase) DiffTemplateVersions(ctx context.Context, templateID string, from string, to string) (*entities.TemplateDiff, errors.BaseError) {
+	template, err := u.repo.GetTemplate(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if to == "" {
+		to = entities.LabelLatest
+	}
+	newer, _, err := u.selectRevision(ctx, template, to)
+	if err != nil {
+		return nil, err
+	}
+	if from == "" {
+		number, _ := entities.ParseVersion(newer.Version)
+		if number <= 1 {
+			return nil, errors.BadRequest(fmt.Sprintf("%s has no previous version", newer.Version))
+		}
+		from = entities.FormatVersion(number - 1)
+	}
+	older, _, err := u.selectRevision(ctx, template, from)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &entities.TemplateDiff{
+		TemplateID: template.ID,
+		From:       older.Version,
+		To:         newer.Version,
+		Content: helper.UnifiedDiff(revisionText(older), revisionText(newer),
+			template.Name+"@"+older.Version, template.Name+"@"+newer.Version),
+		Fields:     []entities.FieldChange{},
+		Variables:  diffVariables(older.Variables, newer.Variables),
+		Parameters: diffParameters(older.Parameters, newer.Parameters),
+	}
+	if older.Syntax != newer.Syntax {
+		diff.Fields = append(diff.Fields, entities.FieldChange{Field: "syntax", From: older.Syntax, To: newer.Syntax})
+	}
+	if currentKind(older.Kind) != currentKind(newer.Kind) {
+		diff.Fields = append(diff.Fields, entities.FieldChange{Field: "kind", From: currentKind(older.Kind), To: currentKind(newer.Kind)})
+	}
+	if older.Tags != nil && newer.Tags != nil {
+		diff.Tags = &entities.TagChange{
+			Added:   missingFrom(older.Tags, newer.Tags),
+			Removed: missingFrom(newer.Tags, older.Tags),
+		}
+	}
+	return diff, nil
+}
+
+// revisionText is the content of a revision as diffed. Chat messages are
+// written one after another under a header naming their role, so a change
+// shows up against the message it belongs to.
+func revisionText(revision *entities.PromptTemplateVersion) string {
+	if currentKind(revision.Kind) != entities.TemplateKindChat {
+		return revision.Content
+	}
+	var text strings.Builder
+	for i, m := range revision.Messages {
+		header := m.Role
+		if m.Name != "" {
+			header += " " + m.Name
+		}
+		if m.Example {
+			header += " (example)"
+		}
+		if i > 0 {
+			text.WriteString("\n")
+		}
+		fmt.Fprintf(&text, "[%s]\n%s\n", header, m.Content)
+	}
+	return text.String()
+}
+
+// diffVariables matches variables by name
+func diffVariables(older []entities.Variable, newer []entities.Variable) []entities.VariableChange {
+	old := make(map[string]*entities.Variable, len(older))
+	for i := range older {
+		old[older[i].Name] = &older[i]
+	}
+
+	changes := []entities.VariableChange{}
+	seen := make(map[string]bool, len(newer))
+	for i := range newer {
+		v := &newer[i]
+		seen[v.Name] = true
+		before, ok := old[v.Name]
+		if !ok {
+			changes = append(changes, entities.VariableChange{Name: v.Name, Change: entities.ChangeAdded, To: v})
+			continue
+		}
+		if fields := variableFields(before, v); len(fields) > 0 {
+			changes = append(changes, entities.VariableChange{Name: v.Name, Change: entities.ChangeModified, Fields: fields, From: before, To: v})
+		}
+	}
+	for i := range older {
+		if !seen[older[i].Name] {
+			changes = append(changes, entities.VariableChange{Name: older[i].Name, Change: entities.ChangeRemoved, From: &older[i]})
+		}
+	}
+	return changes
+}
+
+// variableFields names the attributes of a variable that differ, by their
+// JSON names
+func variableFields(a *entities.Variable, b *entities.Variable) []string {
+	var fields []string
+	ta, va, vb := reflect.TypeOf(*a), reflect.ValueOf(*a), reflect.ValueOf(*b)
+	for i := 0; i < ta.NumField(); i++ {
+		if va.Field(i).Interface() != vb.Field(i).Interface() {
+			name, _, _ := strings.Cut(ta.Field(i).Tag.Get("json"), ",")
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// diffParameters compares model parameters by their JSON fields, so every
+// parameter is covered without listing them here
+func diffParameters(older *entities.ModelParameters, newer *entities.ModelParameters) []entities.FieldChange {
+	a, b := parameterFields(older), parameterFields(newer)
+	names := make([]string, 0, len(a)+len(b))
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := []entities.FieldChange{}
+	for _, name := range names {
+		if !reflect.DeepEqual(a[name], b[name]) {
+			changes = append(changes, entities.FieldChange{Field: name, From: a[name], To: b[name]})
+		}
+	}
+	return changes
+}
+
+func parameterFields(params *entities.ModelParameters) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if params != nil {
+		encoded, _ := json.Marshal(params)
+		_ = json.Unmarshal(encoded, &fields)
+	}
+	return fields
+}
+
+// missingFrom returns the tags of b that are not in a
+func missingFrom(a []string, b []string) []string {
+	in := make(map[string]bool, len(a))
+	for _, tag := range a {
+		in[tag] = true
+	}
+	missing := []string{}
+	for _, tag := range b {
+		if !in[tag] {
+			missing = append(missing, tag)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,473 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+)
+
+type iExperimentRepository interface {
+	CreateExperiment(ctx context.Context, experiment *entities.PromptExperiment) (*entities.PromptExperiment, errors.BaseError)
+	GetExperiment(ctx context.Context, id string) (*entities.PromptExperiment, errors.BaseError)
+	ListExperiments(ctx context.Context, filter *entities.ExperimentFilter) ([]*entities.PromptExperiment, int64, errors.BaseError)
+	UpdateExperiment(ctx context.Context, experiment *entities.PromptExperiment, from []entities.ExperimentStatus) (*entities.PromptExperiment, errors.BaseError)
+	TransitionExperiment(ctx context.Context, id string, from []entities.ExperimentStatus, to entities.ExperimentStatus, winner string) (*entities.PromptExperiment, errors.BaseError)
+	DeleteExperiment(ctx context.Context, id string) errors.BaseError
+	GetAssignment(ctx context.Context, experimentID string, subjectKey string) (*entities.ExperimentAssignment, errors.BaseError)
+	SaveAssignment(ctx context.Context, assignment *entities.ExperimentAssignment, overwrite bool) (*entities.ExperimentAssignment, errors.BaseError)
+	GetAssignmentByID(ctx context.Context, id string) (*entities.ExperimentAssignment, errors.BaseError)
+	CreateOutcome(ctx context.Context, outcome *entities.ExperimentOutcome, holdout bool) (*entities.ExperimentOutcome, errors.BaseError)
+	AggregateOutcomes(ctx context.Context, experimentID string) ([]*entities.OutcomeAggregate, errors.BaseError)
+	CountExposures(ctx context.Context, experimentID string) (map[string]int64, errors.BaseError)
+}
+
+// iTemplateLookup is the part of the template repository experiments need
+type iTemplateLookup interface {
+	GetTemplate(ctx context.Context, id string) (*entities.PromptTemplate, errors.BaseError)
+	GetTemplateVersion(ctx context.Context, templateID string, number int) (*entities.PromptTemplateVersion, errors.BaseError)
+	GetTemplateLabel(ctx context.Context, templateID string, label string) (*entities.TemplateLabel, errors.BaseError)
+	ListTemplateReviews(ctx context.Context, templateID string) ([]*entities.TemplateReview, errors.BaseError)
+}
+
+type experimentUsecase struct {
+	repo      iExperimentRepository
+	templates iTemplateLookup
+	audit     *auditUsecase
+}
+
+func NewExperimentUsecase(repo iExperimentRepository, templates iTemplateLookup, audit *auditUsecase) *experimentUsecase {
+	return &experimentUsecase{repo: repo, templates: templates, audit: audit}
+}
+
+func (u *experimentUsecase) CreateExperiment(ctx context.Context, payload *entities.CreateExperimentPayload) (*entities.PromptExperiment, errors.BaseError) {
+	if payload.Name == "" {
+		return nil, errors.BadRequest("name is required")
+	}
+	variants, err := u.validateVariants(ctx, payload.Variants)
+	if err != nil {
+		return nil, err
+	}
+
+	experiment := &entities.PromptExperiment{
+		Name:             payload.Name,
+		Description:      payload.Description,
+		PromptTemplateID: variants[0].TemplateID,
+		ModelID:          variants[0].ModelID,
+		Config:           payload.Config,
+		Variants:         variants,
+		HoldoutPercent:   payload.HoldoutPercent,
+		Overrides:        payload.Overrides,
+		Status:           entities.ExperimentStatusDraft,
+	}
+	if err := validateTargeting(experiment); err != nil {
+		return nil, err
+	}
+
+	var created *entities.PromptExperiment
+	err = u.audit.audited(ctx, func(ctx context.Context) errors.BaseError {
+		var err errors.BaseError
+		if created, err = u.repo.CreateExperiment(ctx, experiment); err != nil {
+			return err
+		}
+		return u.audit.record(ctx, entities.AuditCreate, entities.AuditResourceExperiment, created.ID, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !payload.Start {
+		return created, nil
+	}
+	return u.StartExperiment(ctx, created.ID)
+}
+
+func (u *experimentUsecase) GetExperiment(ctx context.Context, id string) (*entities.PromptExperiment, errors.BaseError) {
+	return u.repo.GetExperiment(ctx, id)
+}
+
+func (u *experimentUsecase) ListExperiments(ctx context.Context, filter *entities.ExperimentFilter) ([]*entities.PromptExperiment, int64, errors.BaseError) {
+	return u.repo.ListExperiments(ctx, filter)
+}
+
+// UpdateExperiment edits an experiment. Variants can only change while the
+// experiment is a draft, otherwise collected results would be mixed up.
+func (u *experimentUsecase) UpdateExperiment(ctx context.Context, payload *entities.UpdateExperimentPayload) (*entities.PromptExperiment, errors.BaseError) {
+	experiment, err := u.ownedExperiment(ctx, payload.ID)
+	if err != nil {
+		return nil, err
+	}
+	before := snapshot(experiment) // experiment is edited in place below
+
+	from := []entities.ExperimentStatus{
+		entities.ExperimentStatusDraft,
+		entities.ExperimentStatusRunning,
+		entities.ExperimentStatusStopped,
+	}
+	if payload.Name != "" {
+		experiment.Name = payload.Name
+	}
+	if payload.Description != "" {
+		experiment.Description = payload.Description
+	}
+	if payload.Config != nil {
+		experiment.Config = payload.Config
+	}
+	if payload.Overrides != nil {
+		experiment.Overrides = payload.Overrides
+	}
+	if payload.HoldoutPercent != nil && *payload.HoldoutPercent != experiment.HoldoutPercent {
+		if experiment.Status != entities.ExperimentStatusDraft {
+			return nil, errors.Conflict("the holdout can only be changed while the experiment is a draft")
+		}
+		experiment.HoldoutPercent = *payload.HoldoutPercent
+		from = []entities.ExperimentStatus{entities.ExperimentStatusDraft}
+	}
+	if payload.Variants != nil {
+		if experiment.Status != entities.ExperimentStatusDraft {
+			return nil, errors.Conflict("variants can only be changed while the experiment is a draft")
+		}
+		variants, err := u.validateVariants(ctx, payload.Variants)
+		if err != nil {
+			return nil, err
+		}
+		experiment.Variants = variants
+		experiment.PromptTemplateID = variants[0].TemplateID
+		experiment.ModelID = variants[0].ModelID
+		from = []entities.ExperimentStatus{entities.ExperimentStatusDraft}
+	}
+	if err := validateTargeting(experiment); err != nil {
+		return nil, err
+	}
+
+	var updated *entities.PromptExperiment
+	err = u.audit.audited(ctx, func(ctx context.Context) errors.BaseError {
+		var err errors.BaseError
+		if updated, err = u.repo.UpdateExperiment(ctx, experiment, from); err != nil {
+			return err
+		}
+		return u.audit.record(ctx, entities.AuditUpdate, entities.AuditResourceExperiment, updated.ID, before, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (u *experimentUsecase) DeleteExperiment(ctx context.Context, id string) errors.BaseError {
+	before, err := u.ownedExperiment(ctx, id)
+	if err != nil {
+		return err
+	}
+	return u.audit.audited(ctx, func(ctx context.Context) errors.BaseError {
+		if err := u.repo.DeleteExperiment(ctx, id); err != nil {
+			return err
+		}
+		return u.audit.record(ctx, entities.AuditDelete, entities.AuditResourceExperiment, id, before, nil)
+	})
+}
+
+// StartExperiment starts a draft experiment or resumes a stopped one. Every
+// pinned version must have been approved, like the ones production serves.
+func (u *experimentUsecase) StartExperiment(ctx context.Context, id string) (*entities.PromptExperiment, errors.BaseError) {
+	experiment, err := u.repo.GetExperiment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range experiment.Variants {
+		if v.TemplateVersion == "" {
+			continue
+		}
+		if err := u.checkApproved(ctx, v.TemplateID, v.TemplateVersion); err != nil {
+			return nil, err
+		}
+	}
+	return u.transition(ctx, id,
+		[]entities.ExperimentStatus{entities.ExperimentStatusDraft, entities.ExperimentStatusStopped},
+		entities.ExperimentStatusRunning, "")
+}
+
+// StopExperiment pauses a running experiment; subjects get the control
+func (u *experimentUsecase) StopExperiment(ctx context.Context, id string) (*entities.PromptExperiment, errors.BaseError) {
+	return u.transition(ctx, id,
+		[]entities.ExperimentStatus{entities.ExperimentStatusRunning},
+		entities.ExperimentStatusStopped, "")
+}
+
+// CompleteExperiment ends an experiment, optionally declaring a winner by
+// variant key. Variants may share a template, so templates cannot name one.
+func (u *experimentUsecase) CompleteExperiment(ctx context.Context, id string, winner string) (*entities.PromptExperiment, errors.BaseError) {
+	experiment, err := u.ownedExperiment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if winner != "" {
+		if _, ok := experiment.Variant(winner); !ok {
+			return nil, errors.BadRequest(fmt.Sprintf("winner %s is not a variant of this experiment", winner))
+		}
+	}
+
+	return u.transition(ctx, id,
+		[]entities.ExperimentStatus{entities.ExperimentStatusRunning, entities.ExperimentStatusStopped},
+		entities.ExperimentStatusCompleted, winner)
+}
+
+// transition moves an experiment between states and audits the change
+func (u *experimentUsecase) transition(ctx context.Context, id string, from []entities.ExperimentStatus, to entities.ExperimentStatus, winner string) (*entities.PromptExperiment, errors.BaseError) {
+	before, err := u.ownedExperiment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var after *entities.PromptExperiment
+	err = u.audit.audited(ctx, func(ctx context.Context) errors.BaseError {
+		var err errors.BaseError
+		if after, err = u.repo.TransitionExperiment(ctx, id, from, to, winner); err != nil {
+			return err
+		}
+		return u.audit.record(ctx, entities.AuditStatus, entities.AuditResourceExperiment, id, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// ResolveExperiment picks the variant to render for a subject (a session,
+// project or user). While the experiment runs, the choice is sticky: forced
+// overrides come first, then the recorded assignment, then a deterministic
+// hash of the experiment and subject against the variant weights. Outside a
+// running experiment the control variant, or the winner of a completed
+// experiment, is returned.
+func (u *experimentUsecase) ResolveExperiment(ctx context.Context, id string, subjectKey string) (*entities.ExperimentResolution, errors.BaseError) {
+	experiment, err := u.repo.GetExperiment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(experiment.Variants) == 0 {
+		return nil, errors.Internal(fmt.Errorf("experiment %s has no variants", id))
+	}
+
+	switch experiment.Status {
+	case entities.ExperimentStatusRunning:
+	case entities.ExperimentStatusCompleted:
+		if winner, ok := experiment.Variant(experiment.WinnerVariant); ok {
+			return u.resolution(ctx, experiment, winner, nil)
+		}
+		return u.resolution(ctx, experiment, &experiment.Variants[0], nil)
+	default:
+		return u.resolution(ctx, experiment, &experiment.Variants[0], nil)
+	}
+
+	if subjectKey == "" {
+		return nil, errors.BadRequest("subject key is required")
+	}
+
+	assignment, err := u.assign(ctx, experiment, subjectKey)
+	if err != nil {
+		return nil, err
+	}
+	variant, ok := experiment.Variant(assignment.VariantKey)
+	if !ok {
+		return nil, errors.Internal(fmt.Errorf("assigned variant %s no longer exists", assignment.VariantKey))
+	}
+	return u.resolution(ctx, experiment, variant, assignment)
+}
+
+// assign returns the recorded assignment of a subject, creating it on first
+// resolution. Forced overrides replace whatever was recorded before, and a
+// subject whose override was removed is assigned by hash again.
+func (u *experimentUsecase) assign(ctx context.Context, experiment *entities.PromptExperiment, subjectKey string) (*entities.ExperimentAssignment, errors.BaseError) {
+	if forced, ok := experiment.Overrides[subjectKey]; ok {
+		return u.repo.SaveAssignment(ctx, &entities.ExperimentAssignment{
+			ExperimentID: experiment.ID,
+			SubjectKey:   subjectKey,
+			VariantKey:   forced,
+			Forced:       true,
+		}, true)
+	}
+
+	existing, err := u.repo.GetAssignment(ctx, experiment.ID, subjectKey)
+	if err == nil && !existing.Forced {
+		return existing, nil
+	}
+	if err != nil && err.GetCode() != errors.NOT_FOUND {
+		return nil, err
+	}
+
+	assignment := &entities.ExperimentAssignment{
+		ExperimentID: experiment.ID,
+		SubjectKey:   subjectKey,
+	}
+	if inHoldout(experiment, subjectKey) {
+		assignment.VariantKey = experiment.Variants[0].Key
+		assignment.Holdout = true
+	} else {
+		assignment.VariantKey = pickVariant(experiment, subjectKey).Key
+	}
+	return u.repo.SaveAssignment(ctx, assignment, existing != nil)
+}
+
+// resolution describes the prompt to render for a variant. A nil assignment
+// means the subject is served outside a running experiment. Unpinned variants
+// serve the version production does, and only approved versions are served.
+func (u *experimentUsecase) resolution(ctx context.Context, experiment *entities.PromptExperiment, variant *entities.ExperimentVariant, assignment *entities.ExperimentAssignment) (*entities.ExperimentResolution, errors.BaseError) {
+	template, err := u.templates.GetTemplate(ctx, variant.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+
+	version := variant.TemplateVersion
+	if version == "" {
+		if version, err = u.productionVersion(ctx, template); err != nil {
+			return nil, err
+		}
+	}
+	if err := u.checkApproved(ctx, template.ID, version); err != nil {
+		return nil, err
+	}
+
+	// Variant settings override the experiment-wide configuration
+	config := make(map[string]string, len(experiment.Config)+len(variant.Config))
+	for k, v := range experiment.Config {
+		config[k] = v
+	}
+	for k, v := range variant.Config {
+		config[k] = v
+	}
+
+	resolution := &entities.ExperimentResolution{
+		ExperimentID:    experiment.ID,
+		VariantKey:      variant.Key,
+		TemplateID:      template.ID,
+		TemplateName:    template.Name,
+		TemplateVersion: version,
+		ModelID:         variant.ModelID,
+		Config:          config,
+	}
+	if assignment != nil {
+		resolution.AssignmentID = assignment.ID
+		resolution.Holdout = assignment.Holdout
+		resolution.Forced = assignment.Forced
+		resolution.InExperiment = !assignment.Holdout
+	}
+	return resolution, nil
+}
+
+// productionVersion is the version the production label of template points
+// at, or its current version before the label exists
+func (u *experimentUsecase) productionVersion(ctx context.Context, template *entities.PromptTemplate) (string, errors.BaseError) {
+	label, err := u.templates.GetTemplateLabel(ctx, template.ID, entities.LabelProduction)
+	if err != nil {
+		if err.GetCode() != errors.NOT_FOUND {
+			return "", err
+		}
+		return template.Version, nil
+	}
+	return label.Version, nil
+}
+
+// checkApproved fails unless a reviewer approved the version of a template
+func (u *experimentUsecase) checkApproved(ctx context.Context, templateID string, version string) errors.BaseError {
+	reviews, err := u.templates.ListTemplateReviews(ctx, templateID)
+	if err != nil {
+		return err
+	}
+	if !approves(reviews, version) {
+		return errors.Conflict(fmt.Sprintf("template %s %s has not been approved", templateID, version))
+	}
+	return nil
+}
+
+// validateVariants checks the variants of an experiment and normalizes their
+// keys and pinned versions. The first variant is treated as the control.
+func (u *experimentUsecase) validateVariants(ctx context.Context, variants []entities.ExperimentVariant) ([]entities.ExperimentVariant, errors.BaseError) {
+	if len(variants) < 2 {
+		return nil, errors.BadRequest("an experiment needs at least two variants")
+	}
+
+	result := make([]entities.ExperimentVariant, len(variants))
+	keys := make(map[string]bool, len(variants))
+	totalWeight := 0
+	for i, v := range variants {
+		if v.Key == "" {
+			v.Key = string(rune('a' + i))
+		}
+		if keys[v.Key] {
+			return nil, errors.BadRequest(fmt.Sprintf("duplicate variant key: %s", v.Key))
+		}
+		keys[v.Key] = true
+
+		if v.Weight < 0 {
+			return nil, errors.BadRequest(fmt.Sprintf("variant %s: weight must not be negative", v.Key))
+		}
+		totalWeight += v.Weight
+
+		if v.TemplateID == "" {
+			return nil, errors.BadRequest(fmt.Sprintf("variant %s: template id is required", v.Key))
+		}
+		if _, err := u.templates.GetTemplate(ctx, v.TemplateID); err != nil {
+			return nil, err
+		}
+		if v.TemplateVersion != "" {
+			number, ok := entities.ParseVersion(v.TemplateVersion)
+			if !ok {
+				return nil, errors.BadRequest(fmt.Sprintf("variant %s: invalid version %s", v.Key, v.TemplateVersion))
+			}
+			if _, err := u.templates.GetTemplateVersion(ctx, v.TemplateID, number); err != nil {
+				return nil, err
+			}
+			v.TemplateVersion = entities.FormatVersion(number)
+		}
+		result[i] = v
+	}
+
+	if totalWeight == 0 {
+		return nil, errors.BadRequest("at least one variant needs a positive weight")
+	}
+	return result, nil
+}
+
+// validateTargeting checks the holdout share and QA overrides
+func validateTargeting(experiment *entities.PromptExperiment) errors.BaseError {
+	if experiment.HoldoutPercent < 0 || experiment.HoldoutPercent > 100 {
+		return errors.BadRequest("holdout percent must be between 0 and 100")
+	}
+	for subject, key := range experiment.Overrides {
+		if _, ok := experiment.Variant(key); !ok {
+			return errors.BadRequest(fmt.Sprintf("override for %s: unknown variant %s", subject, key))
+		}
+	}
+	return nil
+}
+
+// inHoldout deterministically places a share of subjects in the holdout. It
+// hashes with its own salt so holdout and variant buckets are independent.
+func inHoldout(experiment *entities.PromptExperiment, subjectKey string) bool {
+	if experiment.HoldoutPercent <= 0 {
+		return false
+	}
+	return bucket("holdout", experiment.ID, subjectKey)%100 < uint64(experiment.HoldoutPercent)
+}
+
+// pickVariant maps a subject onto the cumulative variant weights
+func pickVariant(experiment *entities.PromptExperiment, subjectKey string) *entities.ExperimentVariant {
+	total := 0
+	for _, v := range experiment.Variants {
+		total += v.Weight
+	}
+	n := int(bucket("variant", experiment.ID, subjectKey) % uint64(total))
+	for i := range experiment.Variants {
+		if n < experiment.Variants[i].Weight {
+			return &experiment.Variants[i]
+		}
+		n -= experiment.Variants[i].Weight
+	}
+	return &experiment.Variants[0]
+}
+
+func bucket(salt, experimentID, subjectKey string) uint64 {
+	sum := sha256.Sum256([]byte(salt + ":" + experimentID + ":" + subjectKey))
+	return binary.BigEndian.Uint64(sum[:8])
+}
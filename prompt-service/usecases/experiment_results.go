@@ -0,0 +1,234 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/blcvn/backend/services/prompt-service/helper"
+)
+
+const (
+	// resultsConfidence is the confidence level of reported intervals
+	resultsConfidence = 0.95
+	// significanceLevel is the p-value below which a difference is significant
+	significanceLevel = 1 - resultsConfidence
+)
+
+// RecordOutcome stores an outcome against an assignment. Conversions default
+// to a value of 1, a subject only counting as converted with a positive value,
+// and feedback must be 1 (thumbs up) or 0 (thumbs down).
+func (u *experimentUsecase) RecordOutcome(ctx context.Context, payload *entities.RecordOutcomePayload) (*entities.ExperimentOutcome, errors.BaseError) {
+	if payload.AssignmentID == "" {
+		return nil, errors.BadRequest("assignment id is required")
+	}
+	if payload.Metric == "" {
+		return nil, errors.BadRequest("metric is required")
+	}
+
+	var value float64
+	switch payload.Kind {
+	case entities.OutcomeKindScore:
+		if payload.Value == nil {
+			return nil, errors.BadRequest("value is required for score outcomes")
+		}
+		value = *payload.Value
+	case entities.OutcomeKindFeedback:
+		if payload.Value == nil || (*payload.Value != 0 && *payload.Value != 1) {
+			return nil, errors.BadRequest("feedback value must be 1 (up) or 0 (down)")
+		}
+		value = *payload.Value
+	case entities.OutcomeKindConversion:
+		value = 1
+		if payload.Value != nil {
+			value = *payload.Value
+		}
+	default:
+		return nil, errors.BadRequest(fmt.Sprintf("invalid outcome kind: %s", payload.Kind))
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, errors.BadRequest("value must be a finite number")
+	}
+
+	experiment, err := u.repo.GetExperiment(ctx, payload.ExperimentID)
+	if err != nil {
+		return nil, err
+	}
+	if experiment.Status == entities.ExperimentStatusDraft {
+		return nil, errors.Conflict("outcomes cannot be recorded for a draft experiment")
+	}
+
+	assignment, err := u.repo.GetAssignmentByID(ctx, payload.AssignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if assignment.ExperimentID != experiment.ID {
+		return nil, errors.BadRequest("assignment does not belong to this experiment")
+	}
+
+	return u.repo.CreateOutcome(ctx, &entities.ExperimentOutcome{
+		ExperimentID: experiment.ID,
+		AssignmentID: assignment.ID,
+		VariantKey:   assignment.VariantKey,
+		Metric:       payload.Metric,
+		Kind:         payload.Kind,
+		Value:        value,
+	}, assignment.Holdout)
+}
+
+// GetExperimentResults aggregates the recorded outcomes per metric and
+// variant and compares every variant with the control. Scores use Welch's
+// t-test; feedback and conversions use a two-proportion z-test, with
+// conversions counted against every subject exposed to the variant.
+func (u *experimentUsecase) GetExperimentResults(ctx context.Context, id string) (*entities.ExperimentResults, errors.BaseError) {
+	experiment, err := u.repo.GetExperiment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(experiment.Variants) == 0 {
+		return nil, errors.Internal(fmt.Errorf("experiment %s has no variants", id))
+	}
+
+	aggregates, err := u.repo.AggregateOutcomes(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	exposures, err := u.repo.CountExposures(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group the aggregates by metric and kind, keeping the order they were
+	// returned in; outcomes of different kinds are never pooled
+	var metrics []metricKey
+	byMetric := make(map[metricKey]map[string]*entities.OutcomeAggregate)
+	for _, a := range aggregates {
+		key := metricKey{metric: a.Metric, kind: a.Kind}
+		if _, ok := byMetric[key]; !ok {
+			metrics = append(metrics, key)
+			byMetric[key] = make(map[string]*entities.OutcomeAggregate)
+		}
+		byMetric[key][a.VariantKey] = a
+	}
+
+	results := &entities.ExperimentResults{
+		ExperimentID: experiment.ID,
+		Status:       experiment.Status,
+		Confidence:   resultsConfidence,
+		Exposures:    make(map[string]int64, len(experiment.Variants)),
+		Metrics:      make([]entities.MetricResults, 0, len(metrics)),
+	}
+	for _, v := range experiment.Variants {
+		results.Exposures[v.Key] = exposures[v.Key]
+	}
+	for _, key := range metrics {
+		results.Metrics = append(results.Metrics, metricResults(experiment, key.metric, key.kind, byMetric[key], exposures))
+	}
+	return results, nil
+}
+
+// metricKey identifies the outcomes analysed together
+type metricKey struct {
+	metric string
+	kind   entities.OutcomeKind
+}
+
+// metricResults computes the statistics of one metric. Variants without any
+// outcome are reported with an empty sample.
+func metricResults(experiment *entities.PromptExperiment, metric string, kind entities.OutcomeKind, aggregates map[string]*entities.OutcomeAggregate, exposures map[string]int64) entities.MetricResults {
+	result := entities.MetricResults{
+		Metric:      metric,
+		Kind:        kind,
+		Variants:    make([]entities.VariantStats, len(experiment.Variants)),
+		Comparisons: make([]entities.VariantComparison, 0, len(experiment.Variants)-1),
+	}
+
+	samples := make([]sample, len(experiment.Variants))
+	for i, v := range experiment.Variants {
+		a := aggregates[v.Key]
+		if a == nil {
+			a = &entities.OutcomeAggregate{}
+		}
+		samples[i] = newSample(kind, a, exposures[v.Key])
+		result.Variants[i] = samples[i].stats(v.Key)
+	}
+
+	control := samples[0]
+	for i := 1; i < len(samples); i++ {
+		result.Comparisons = append(result.Comparisons,
+			compareSamples(experiment.Variants[0].Key, experiment.Variants[i].Key, control, samples[i]))
+	}
+	return result
+}
+
+// sample is the data of one variant needed for the significance tests
+type sample struct {
+	binary    bool
+	n         int64
+	successes int64
+	mean      float64
+	variance  float64
+}
+
+func newSample(kind entities.OutcomeKind, a *entities.OutcomeAggregate, exposed int64) sample {
+	switch kind {
+	case entities.OutcomeKindFeedback:
+		return binarySample(int64(math.Round(a.Sum)), a.Count)
+	case entities.OutcomeKindConversion:
+		// Only a positive value converts a subject. Every converted subject
+		// was exposed; guard against a stale count
+		if exposed < a.Converted {
+			exposed = a.Converted
+		}
+		return binarySample(a.Converted, exposed)
+	default:
+		return sample{n: a.Count, mean: a.Mean, variance: a.Variance}
+	}
+}
+
+func binarySample(successes, n int64) sample {
+	s := sample{binary: true, n: n, successes: successes}
+	if n > 0 {
+		p := float64(successes) / float64(n)
+		s.mean = p
+		s.variance = p * (1 - p)
+	}
+	return s
+}
+
+func (s sample) stats(variantKey string) entities.VariantStats {
+	stats := entities.VariantStats{
+		VariantKey: variantKey,
+		SampleSize: s.n,
+		Mean:       s.mean,
+		StdDev:     math.Sqrt(s.variance),
+	}
+	if s.binary {
+		stats.CILow, stats.CIHigh = helper.ProportionInterval(s.successes, s.n)
+	} else {
+		stats.CILow, stats.CIHigh = helper.MeanInterval(s.mean, s.variance, s.n)
+	}
+	return stats
+}
+
+func compareSamples(baselineKey, variantKey string, baseline, variant sample) entities.VariantComparison {
+	comparison := entities.VariantComparison{
+		VariantKey:  variantKey,
+		BaselineKey: baselineKey,
+	}
+	if baseline.binary {
+		comparison.Test = "two_proportion_z"
+		comparison.Statistic, comparison.PValue = helper.TwoProportionZTest(baseline.successes, baseline.n, variant.successes, variant.n)
+	} else {
+		comparison.Test = "welch_t"
+		comparison.Statistic, comparison.DegreesOfFreedom, comparison.PValue = helper.WelchTTest(
+			baseline.mean, baseline.variance, baseline.n, variant.mean, variant.variance, variant.n)
+	}
+	if baseline.mean != 0 {
+		comparison.Lift = (variant.mean - baseline.mean) / math.Abs(baseline.mean)
+	}
+	comparison.Significant = comparison.PValue < significanceLevel
+	return comparison
+}
@@ -0,0 +1,94 @@
+package usecases
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/blcvn/backend/services/prompt-service/entities"
+)
+
+func TestPickVariant(t *testing.T) {
+	const subjects = 20000
+	tests := []struct {
+		name    string
+		weights []int
+	}{
+		{"even split", []int{50, 50}},
+		{"uneven split", []int{70, 20, 10}},
+		{"zero weight", []int{1, 0, 1}},
+		{"single variant", []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			experiment := &entities.PromptExperiment{ID: "exp-" + tt.name}
+			total := 0
+			for i, weight := range tt.weights {
+				experiment.Variants = append(experiment.Variants, entities.ExperimentVariant{Key: fmt.Sprintf("v%d", i), Weight: weight})
+				total += weight
+			}
+
+			counts := make(map[string]int)
+			for i := 0; i < subjects; i++ {
+				subject := fmt.Sprintf("user-%d", i)
+				variant := pickVariant(experiment, subject)
+				if again := pickVariant(experiment, subject); again.Key != variant.Key {
+					t.Fatalf("subject %s moved from %s to %s", subject, variant.Key, again.Key)
+				}
+				counts[variant.Key]++
+			}
+			for i, weight := range tt.weights {
+				key := fmt.Sprintf("v%d", i)
+				share := float64(counts[key]) / subjects
+				if want := float64(weight) / float64(total); math.Abs(share-want) > 0.02 {
+					t.Errorf("variant %s got %.3f of subjects, want %.3f", key, share, want)
+				}
+			}
+		})
+	}
+}
+
+func TestInHoldout(t *testing.T) {
+	const subjects = 20000
+	for _, percent := range []int{0, 10, 50, 100} {
+		t.Run(fmt.Sprintf("%d percent", percent), func(t *testing.T) {
+			experiment := &entities.PromptExperiment{ID: "exp-holdout", HoldoutPercent: percent}
+			held := 0
+			for i := 0; i < subjects; i++ {
+				if inHoldout(experiment, fmt.Sprintf("user-%d", i)) {
+					held++
+				}
+			}
+			if share := float64(held) / subjects; math.Abs(share-float64(percent)/100) > 0.02 {
+				t.Errorf("holdout took %.3f of subjects, want %.2f", share, float64(percent)/100)
+			}
+		})
+	}
+}
+
+func TestBucketsAreIndependent(t *testing.T) {
+	// Holdout and variant buckets use different salts, so the holdout does
+	// not skew the split among the remaining subjects
+	experiment := &entities.PromptExperiment{
+		ID:             "exp-independent",
+		HoldoutPercent: 50,
+		Variants:       []entities.ExperimentVariant{{Key: "a", Weight: 1}, {Key: "b", Weight: 1}},
+	}
+	counts := make(map[string]int)
+	remaining := 0
+	for i := 0; i < 20000; i++ {
+		subject := fmt.Sprintf("user-%d", i)
+		if inHoldout(experiment, subject) {
+			continue
+		}
+		remaining++
+		counts[pickVariant(experiment, subject).Key]++
+	}
+	if share := float64(counts["a"]) / float64(remaining); math.Abs(share-0.5) > 0.02 {
+		t.Errorf("variant a got %.3f of subjects outside the holdout, want 0.5", share)
+	}
+
+	if bucket("variant", "exp-1", "user-1") == bucket("variant", "exp-2", "user-1") {
+		t.Error("bucket() does not depend on the experiment")
+	}
+}
@@ -0,0 +1,156 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/blcvn/backend/services/prompt-service/helper"
+)
+
+// LintTemplate checks template content against its declared variables
+// without saving it, reporting the issues CreateTemplate and UpdateTemplate
+// would reject or warn about.
+func (u *promptUsecase) LintTemplate(ctx context.Context, payload *entities.LintTemplatePayload) (*entities.LintReport, errors.BaseError) {
+	content, err := chatContent(&payload.Kind, payload.Content, payload.Messages)
+	if err != nil {
+		return nil, err
+	}
+	if content == "" {
+		return nil, errors.BadRequest("content is required")
+	}
+	if payload.Kind == "" {
+		payload.Kind = entities.TemplateKindText
+	}
+	locale, err := u.templateLocale(payload.Locale)
+	if err != nil {
+		return nil, err
+	}
+	payload.Locale = locale
+	if !payload.Kind.IsValid() {
+		return nil, errors.BadRequest(fmt.Sprintf("unsupported template kind: %s", payload.Kind))
+	}
+	if payload.Syntax == "" {
+		payload.Syntax = entities.TemplateSyntaxGo
+	}
+	if !payload.Syntax.IsValid() {
+		return nil, errors.BadRequest(fmt.Sprintf("unsupported template syntax: %s", payload.Syntax))
+	}
+	if errs := helper.ValidateVariableDeclarations(payload.Variables); len(errs) > 0 {
+		return nil, errors.BadRequest("invalid variables: " + strings.Join(errs, "; "))
+	}
+
+	issues := u.lint(ctx, payload.Name, payload.Locale, content, payload.Kind, payload.Syntax, helper.DeclaredVariables(payload.Variables))
+	report := &entities.LintReport{Valid: true, Issues: []entities.LintIssue{}}
+	report.Issues = append(report.Issues, issues...)
+	for _, issue := range issues {
+		if issue.Severity == entities.LintError {
+			report.Valid = false
+		}
+	}
+	return report, nil
+}
+
+// checkLint lints content that is about to be saved. Errors reject the save;
+// warnings are returned so they can be shown with the saved template.
+func (u *promptUsecase) checkLint(ctx context.Context, name string, locale string, content string, kind entities.TemplateKind, syntax entities.TemplateSyntax, declared []entities.Variable) ([]entities.LintIssue, errors.BaseError) {
+	var problems []string
+	var warnings []entities.LintIssue
+	for _, issue := range u.lint(ctx, name, locale, content, kind, syntax, declared) {
+		if issue.Severity == entities.LintError {
+			problems = append(problems, issue.String())
+		} else {
+			warnings = append(warnings, issue)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.BadRequest("template has lint errors: " + strings.Join(problems, "; "))
+	}
+	return warnings, nil
+}
+
+// lint compares the variables referenced by content, including through its
+// partials, with the declared ones. Templates that declare no variables at
+// all predate declarations, so their undeclared references are only warnings.
+func (u *promptUsecase) lint(ctx context.Context, name string, locale string, content string, kind entities.TemplateKind, syntax entities.TemplateSyntax, declared []entities.Variable) []entities.LintIssue {
+	segments, err := templateSegments(content, kind)
+	if err != nil {
+		return []entities.LintIssue{{Severity: entities.LintError, Code: entities.LintCodeSyntax, Text: err.Error()}}
+	}
+
+	var issues []entities.LintIssue
+	var refs []entities.LintIssue // one per reference, positioned
+	parsed := true
+	for i, s := range segments {
+		message := 0
+		if kind == entities.TemplateKindChat {
+			message = i + 1
+		}
+		found, err := u.engine.ExtractVariableRefs(s.content, syntax)
+		if err != nil {
+			line, col := helper.ErrorPosition(err)
+			issues = append(issues, entities.LintIssue{
+				Severity: entities.LintError,
+				Code:     entities.LintCodeSyntax,
+				Text:     err.Error(),
+				Message:  message,
+				Line:     line,
+				Column:   col,
+			})
+			parsed = false
+			continue
+		}
+		for _, ref := range found {
+			refs = append(refs, entities.LintIssue{Variable: ref.Name, Message: message, Line: ref.Line, Column: ref.Column})
+		}
+	}
+	if !parsed {
+		return issues
+	}
+
+	// Partials render with the values of the including template, so their
+	// references count as uses
+	used := make(map[string]bool)
+	partials, perr := u.resolvePartials(ctx, name, u.locales.Chain(locale), content, kind, syntax, entities.LabelLatest)
+	if perr != nil {
+		issues = append(issues, entities.LintIssue{Severity: entities.LintError, Code: entities.LintCodeInclude, Text: perr.Error()})
+	}
+	for _, partial := range partials {
+		found, _ := u.engine.ExtractVariableRefs(partial.Content, partial.Syntax)
+		for _, ref := range found {
+			used[ref.Name] = true
+		}
+	}
+
+	isDeclared := make(map[string]bool, len(declared))
+	for _, v := range declared {
+		isDeclared[v.Name] = true
+	}
+	severity := entities.LintWarning
+	if len(declared) > 0 {
+		severity = entities.LintError
+	}
+	for _, ref := range refs {
+		used[ref.Variable] = true
+		if isDeclared[ref.Variable] {
+			continue
+		}
+		ref.Severity = severity
+		ref.Code = entities.LintCodeUndeclared
+		ref.Text = fmt.Sprintf("variable %s is not declared", ref.Variable)
+		issues = append(issues, ref)
+	}
+	for _, v := range declared {
+		if !used[v.Name] {
+			issues = append(issues, entities.LintIssue{
+				Severity: entities.LintWarning,
+				Code:     entities.LintCodeUnused,
+				Text:     fmt.Sprintf("variable %s is declared but never used", v.Name),
+				Variable: v.Name,
+			})
+		}
+	}
+	return issues
+}
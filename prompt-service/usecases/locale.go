@@ -0,0 +1,48 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/blcvn/backend/services/prompt-service/helper"
+)
+
+// templateLocale normalizes the locale of a template variant being saved.
+// Empty means the default locale; other locales must be supported, so that a
+// typo does not create a variant no render ever falls back to.
+func (u *promptUsecase) templateLocale(locale string) (string, errors.BaseError) {
+	if locale == "" {
+		return u.locales.Default, nil
+	}
+	normalized, ok := helper.NormalizeLocale(locale)
+	if !ok {
+		return "", errors.BadRequest(fmt.Sprintf("invalid locale: %s", locale))
+	}
+	if !u.locales.IsSupported(normalized) {
+		return "", errors.BadRequest(fmt.Sprintf("unsupported locale: %s (supported: %v)", normalized, u.locales.Supported))
+	}
+	return normalized, nil
+}
+
+// annotateLocales sets the locales each listed template's name has variants
+// in and the supported ones it is missing.
+func (u *promptUsecase) annotateLocales(ctx context.Context, templates []*entities.PromptTemplate) errors.BaseError {
+	if len(templates) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(templates))
+	for _, t := range templates {
+		names = append(names, t.Name)
+	}
+	locales, err := u.repo.ListTemplateLocales(ctx, names)
+	if err != nil {
+		return err
+	}
+	for _, t := range templates {
+		t.Locales = locales[t.Name]
+		t.MissingLocales = u.locales.Missing(t.Locales)
+	}
+	return nil
+}
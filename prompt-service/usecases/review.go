@@ -0,0 +1,159 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+)
+
+// reviewTransition is a move of the review workflow
+type reviewTransition struct {
+	from     []entities.TemplateStatus
+	to       entities.TemplateStatus
+	reviewer bool // only callers with a reviewer role may make it
+}
+
+var reviewTransitions = map[entities.TemplateReviewAction]reviewTransition{
+	entities.ReviewSubmit: {
+		from: []entities.TemplateStatus{entities.TemplateStatusDraft},
+		to:   entities.TemplateStatusReview,
+	},
+	entities.ReviewApprove: {
+		from:     []entities.TemplateStatus{entities.TemplateStatusReview},
+		to:       entities.TemplateStatusActive,
+		reviewer: true,
+	},
+	entities.ReviewReject: {
+		from:     []entities.TemplateStatus{entities.TemplateStatusReview},
+		to:       entities.TemplateStatusDraft,
+		reviewer: true,
+	},
+	entities.ReviewActivate: {
+		from: []entities.TemplateStatus{entities.TemplateStatusDraft, entities.TemplateStatusArchived},
+		to:   entities.TemplateStatusActive,
+	},
+	entities.ReviewArchive: {
+		from: []entities.TemplateStatus{entities.TemplateStatusDraft, entities.TemplateStatusReview, entities.TemplateStatusActive},
+		to:   entities.TemplateStatusArchived,
+	},
+	entities.ReviewReopen: {
+		from: []entities.TemplateStatus{entities.TemplateStatusReview, entities.TemplateStatusActive, entities.TemplateStatusArchived},
+		to:   entities.TemplateStatusDraft,
+	},
+}
+
+// ReviewTemplate moves a template through the review workflow: a draft is
+// submitted for review, then approved, which makes it active, or rejected
+// back to draft with a comment. Only reviewers may approve or reject, and a
+// template only becomes active at a version a reviewer approved. Saving a
+// new version sends a template under review or active back to draft.
+func (u *promptUsecase) ReviewTemplate(ctx context.Context, payload *entities.ReviewTemplatePayload) (*entities.PromptTemplate, errors.BaseError) {
+	transition, ok := reviewTransitions[payload.Action]
+	if !ok {
+		return nil, errors.BadRequest(fmt.Sprintf("invalid review action: %s", payload.Action))
+	}
+	if transition.reviewer && !payload.Actor.HasRole(u.reviewerRoles...) {
+		return nil, errors.Forbidden(fmt.Sprintf("only reviewers can %s templates", payload.Action))
+	}
+	payload.Comment = strings.TrimSpace(payload.Comment)
+	if payload.Action == entities.ReviewReject && payload.Comment == "" {
+		return nil, errors.BadRequest("a comment is required to reject a template")
+	}
+
+	template, err := u.ownedTemplate(ctx, payload.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasTemplateStatus(transition.from, template.Status) {
+		return nil, errors.Conflict(fmt.Sprintf("cannot %s a template that is %s", payload.Action, template.Status))
+	}
+	if payload.Action == entities.ReviewActivate {
+		approved, err := u.isApproved(ctx, template.ID, template.Version)
+		if err != nil {
+			return nil, err
+		}
+		if !approved {
+			return nil, errors.Conflict(fmt.Sprintf("%s has not been approved; submit it for review", template.Version))
+		}
+	}
+
+	var reviewed *entities.PromptTemplate
+	err = u.audit.audited(ctx, func(ctx context.Context) errors.BaseError {
+		var err errors.BaseError
+		reviewed, err = u.repo.ReviewTemplate(ctx, &entities.TemplateReview{
+			TemplateID: template.ID,
+			Version:    template.Version,
+			Action:     payload.Action,
+			To:         transition.to,
+			Actor:      payload.Actor.ID,
+			Comment:    payload.Comment,
+		}, transition.from)
+		if err != nil {
+			return err
+		}
+		return u.audit.record(ctx, entities.AuditStatus, entities.AuditResourceTemplate, template.ID, template, reviewed)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reviewed, nil
+}
+
+// ListTemplateReviews returns the review history of a template, newest first
+func (u *promptUsecase) ListTemplateReviews(ctx context.Context, templateID string) ([]*entities.TemplateReview, errors.BaseError) {
+	if _, err := u.repo.GetTemplate(ctx, templateID); err != nil {
+		return nil, err
+	}
+	return u.repo.ListTemplateReviews(ctx, templateID)
+}
+
+// setTemplateStatus applies a status set through UpdateTemplate as the
+// matching review action. Setting the current status does nothing.
+func (u *promptUsecase) setTemplateStatus(ctx context.Context, id string, status entities.TemplateStatus, actor entities.Actor) errors.BaseError {
+	action, ok := entities.TemplateStatusAction(status)
+	if !ok {
+		return errors.BadRequest(fmt.Sprintf("invalid status: %s", status))
+	}
+	template, err := u.ownedTemplate(ctx, id)
+	if err != nil {
+		return err
+	}
+	if template.Status == status {
+		return nil
+	}
+	_, err = u.ReviewTemplate(ctx, &entities.ReviewTemplatePayload{ID: id, Action: action, Actor: actor})
+	return err
+}
+
+// isApproved reports whether a reviewer approved the version, or it restored
+// a version a reviewer approved
+func (u *promptUsecase) isApproved(ctx context.Context, templateID string, version string) (bool, errors.BaseError) {
+	reviews, err := u.repo.ListTemplateReviews(ctx, templateID)
+	if err != nil {
+		return false, err
+	}
+	return approves(reviews, version), nil
+}
+
+// approves reports whether the review history approves the version
+func approves(reviews []*entities.TemplateReview, version string) bool {
+	for _, review := range reviews {
+		approval := review.Action == entities.ReviewApprove || review.Action == entities.ReviewRestore
+		if approval && review.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTemplateStatus(statuses []entities.TemplateStatus, status entities.TemplateStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
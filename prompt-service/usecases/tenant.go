@@ -0,0 +1,36 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+)
+
+// ownedTemplate returns a template the caller may change. Tenants see the
+// global templates but cannot change them; they override one by creating a
+// template of the same name.
+func (u *promptUsecase) ownedTemplate(ctx context.Context, id string) (*entities.PromptTemplate, errors.BaseError) {
+	template, err := u.repo.GetTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if template.TenantID != entities.RequestFromContext(ctx).Actor.TenantID {
+		return nil, errors.Forbidden(fmt.Sprintf("%s is a global template; create a template named %s to override it", template.Name, template.Name))
+	}
+	return template, nil
+}
+
+// ownedExperiment returns an experiment the caller may change. Like global
+// templates, global experiments are visible to tenants but read-only.
+func (u *experimentUsecase) ownedExperiment(ctx context.Context, id string) (*entities.PromptExperiment, errors.BaseError) {
+	experiment, err := u.repo.GetExperiment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if experiment.TenantID != entities.RequestFromContext(ctx).Actor.TenantID {
+		return nil, errors.Forbidden(fmt.Sprintf("%s is a global experiment and cannot be changed by a tenant", experiment.Name))
+	}
+	return experiment, nil
+}
@@ -0,0 +1,57 @@
+package usecases
+
+import (
+	"fmt"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+)
+
+const (
+	// messageTokenOverhead approximates the tokens chat formats spend on the
+	// role and delimiters of each message
+	messageTokenOverhead = 3
+	// replyTokenOverhead approximates the tokens that prime the reply
+	replyTokenOverhead = 3
+)
+
+// checkBudget estimates the size of a rendered prompt when asked to or when
+// its parameters declare a budget, and enforces that budget.
+func (u *promptUsecase) checkBudget(result *entities.RenderedPrompt, count bool) errors.BaseError {
+	params := result.Parameters
+	var budget *int
+	if params != nil {
+		budget = params.MaxInputTokens
+	}
+	if !count && budget == nil {
+		return nil
+	}
+
+	tokens := u.countTokens(result)
+	result.Tokens = &entities.TokenEstimate{
+		Count:     tokens,
+		Tokenizer: u.tokenizer.Name(),
+		Budget:    budget,
+	}
+	if budget == nil || tokens <= *budget {
+		return nil
+	}
+
+	msg := fmt.Sprintf("rendered prompt is about %d tokens, over the budget of %d", tokens, *budget)
+	if params.BudgetAction == entities.BudgetActionWarn {
+		result.Warnings = append(result.Warnings, msg)
+		return nil
+	}
+	return errors.BadRequest(msg)
+}
+
+func (u *promptUsecase) countTokens(result *entities.RenderedPrompt) int {
+	if result.Messages == nil {
+		return u.tokenizer.CountTokens(result.Content)
+	}
+	tokens := replyTokenOverhead
+	for _, m := range result.Messages {
+		tokens += messageTokenOverhead + u.tokenizer.CountTokens(m.Content)
+	}
+	return tokens
+}
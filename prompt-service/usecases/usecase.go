@@ -2,35 +2,136 @@ package usecases
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/blcvn/backend/services/prompt-service/common/errors"
 	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/blcvn/backend/services/prompt-service/helper"
 )
 
 type iPromptRepository interface {
 	CreateTemplate(ctx context.Context, payload *entities.CreateTemplatePayload) (*entities.PromptTemplate, errors.BaseError)
 	GetTemplate(ctx context.Context, id string) (*entities.PromptTemplate, errors.BaseError)
-	GetTemplateByName(ctx context.Context, name string) (*entities.PromptTemplate, errors.BaseError)
+	GetTemplateByName(ctx context.Context, name string, locales []string) (*entities.PromptTemplate, errors.BaseError)
+	ListTemplateLocales(ctx context.Context, names []string) (map[string][]string, errors.BaseError)
 	ListTemplates(ctx context.Context, filter *entities.TemplateFilter) ([]*entities.PromptTemplate, int64, errors.BaseError)
+	SearchTemplates(ctx context.Context, filter *entities.TemplateFilter) ([]*entities.TemplateSearchHit, int64, errors.BaseError)
 	UpdateTemplate(ctx context.Context, payload *entities.UpdateTemplatePayload) (*entities.PromptTemplate, errors.BaseError)
 	DeleteTemplate(ctx context.Context, id string) errors.BaseError
+	ListTemplateVersions(ctx context.Context, templateID string) ([]*entities.PromptTemplateVersion, errors.BaseError)
+	GetTemplateVersion(ctx context.Context, templateID string, number int) (*entities.PromptTemplateVersion, errors.BaseError)
+	ListIncludingTemplates(ctx context.Context, partialName string) ([]*entities.TemplateDependent, errors.BaseError)
+	ListTags(ctx context.Context) ([]*entities.TagCount, errors.BaseError)
+	ListTemplateLabels(ctx context.Context, templateID string) ([]*entities.TemplateLabel, errors.BaseError)
+	GetTemplateLabel(ctx context.Context, templateID string, label string) (*entities.TemplateLabel, errors.BaseError)
+	SetTemplateLabel(ctx context.Context, templateID string, label string, number int, from int) (*entities.TemplateLabel, errors.BaseError)
+	DeleteTemplateLabel(ctx context.Context, templateID string, label string) errors.BaseError
+	ReviewTemplate(ctx context.Context, review *entities.TemplateReview, from []entities.TemplateStatus) (*entities.PromptTemplate, errors.BaseError)
+	ListTemplateReviews(ctx context.Context, templateID string) ([]*entities.TemplateReview, errors.BaseError)
 }
 
+const (
+	// maxTagLength bounds the length of a single tag
+	maxTagLength = 64
+	// maxQueryLength bounds the length of a full-text search query
+	maxQueryLength = 256
+)
+
 type promptUsecase struct {
-	repo iPromptRepository
+	repo          iPromptRepository
+	engine        *helper.TemplateEngine
+	tokenizer     helper.Tokenizer
+	locales       *helper.Locales
+	reviewerRoles []string
+	audit         *auditUsecase
 }
 
-func NewPromptUsecase(repo iPromptRepository) *promptUsecase {
-	return &promptUsecase{repo: repo}
+// NewPromptUsecase creates the usecase; a nil tokenizer falls back to the
+// heuristic estimate, nil locales support only the default locale, without
+// reviewer roles entities.RoleReviewer may approve templates and without an
+// audit usecase changes are not audited.
+func NewPromptUsecase(repo iPromptRepository, tokenizer helper.Tokenizer, locales *helper.Locales, reviewerRoles []string, audit *auditUsecase) *promptUsecase {
+	if tokenizer == nil {
+		tokenizer = helper.HeuristicTokenizer{}
+	}
+	if locales == nil {
+		locales = helper.DefaultLocales()
+	}
+	if len(reviewerRoles) == 0 {
+		reviewerRoles = []string{entities.RoleReviewer}
+	}
+	return &promptUsecase{
+		repo:          repo,
+		engine:        helper.NewTemplateEngine(),
+		tokenizer:     tokenizer,
+		locales:       locales,
+		reviewerRoles: reviewerRoles,
+		audit:         audit,
+	}
 }
 
 func (u *promptUsecase) CreateTemplate(ctx context.Context, payload *entities.CreateTemplatePayload) (*entities.PromptTemplate, errors.BaseError) {
+	content, err := chatContent(&payload.Kind, payload.Content, payload.Messages)
+	if err != nil {
+		return nil, err
+	}
+	payload.Content = content
 	if payload.Name == "" || payload.Content == "" {
 		return nil, errors.BadRequest("name and content are required")
 	}
-	return u.repo.CreateTemplate(ctx, payload)
+	if payload.Kind == "" {
+		payload.Kind = entities.TemplateKindText
+	}
+	locale, err := u.templateLocale(payload.Locale)
+	if err != nil {
+		return nil, err
+	}
+	payload.Locale = locale
+	if err := u.validateSyntax(payload.Content, payload.Kind, &payload.Syntax); err != nil {
+		return nil, err
+	}
+	if errs := helper.ValidateVariableDeclarations(payload.Variables); len(errs) > 0 {
+		return nil, errors.BadRequest("invalid variables: " + strings.Join(errs, "; "))
+	}
+	if errs := payload.Parameters.Validate(); len(errs) > 0 {
+		return nil, errors.BadRequest("invalid parameters: " + strings.Join(errs, "; "))
+	}
+	tags, err := normalizeTags(payload.Tags)
+	if err != nil {
+		return nil, err
+	}
+	payload.Tags = tags
+	includes, err := u.includesOf(ctx, payload.Name, payload.Locale, payload.Content, payload.Kind, payload.Syntax)
+	if err != nil {
+		return nil, err
+	}
+	payload.Includes = includes
+	var explicit []entities.Variable
+	if len(payload.Variables) > 0 {
+		explicit = payload.Variables
+	}
+	vars, warnings, err := u.declareVariables(ctx, payload.Name, payload.Locale, payload.Content, payload.Kind, payload.Syntax, explicit, nil)
+	if err != nil {
+		return nil, err
+	}
+	payload.Variables = vars
+
+	var template *entities.PromptTemplate
+	err = u.audit.audited(ctx, func(ctx context.Context) errors.BaseError {
+		var err errors.BaseError
+		if template, err = u.repo.CreateTemplate(ctx, payload); err != nil {
+			return err
+		}
+		return u.audit.record(ctx, entities.AuditCreate, entities.AuditResourceTemplate, template.ID, nil, template)
+	})
+	if err != nil {
+		return nil, err
+	}
+	template.Lint = warnings
+	return template, nil
 }
 
 func (u *promptUsecase) GetTemplate(ctx context.Context, id string) (*entities.PromptTemplate, errors.BaseError) {
@@ -38,43 +139,722 @@ func (u *promptUsecase) GetTemplate(ctx context.Context, id string) (*entities.P
 }
 
 func (u *promptUsecase) ListTemplates(ctx context.Context, filter *entities.TemplateFilter) ([]*entities.PromptTemplate, int64, errors.BaseError) {
-	return u.repo.ListTemplates(ctx, filter)
+	if err := normalizeFilter(filter); err != nil {
+		return nil, 0, err
+	}
+	templates, total, err := u.repo.ListTemplates(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := u.annotateLocales(ctx, templates); err != nil {
+		return nil, 0, err
+	}
+	return templates, total, nil
 }
 
+// SearchTemplates ranks templates against filter.Query, restricted by the
+// status and tag filters.
+func (u *promptUsecase) SearchTemplates(ctx context.Context, filter *entities.TemplateFilter) ([]*entities.TemplateSearchHit, int64, errors.BaseError) {
+	filter.Query = strings.TrimSpace(filter.Query)
+	if filter.Query == "" {
+		return nil, 0, errors.BadRequest("search query is required")
+	}
+	if utf8.RuneCountInString(filter.Query) > maxQueryLength {
+		return nil, 0, errors.BadRequest(fmt.Sprintf("search query is longer than %d characters", maxQueryLength))
+	}
+	if err := normalizeFilter(filter); err != nil {
+		return nil, 0, err
+	}
+	hits, total, err := u.repo.SearchTemplates(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	templates := make([]*entities.PromptTemplate, 0, len(hits))
+	for _, hit := range hits {
+		templates = append(templates, hit.Template)
+	}
+	if err := u.annotateLocales(ctx, templates); err != nil {
+		return nil, 0, err
+	}
+	return hits, total, nil
+}
+
+func (u *promptUsecase) ListTags(ctx context.Context) ([]*entities.TagCount, errors.BaseError) {
+	return u.repo.ListTags(ctx)
+}
+
+// UpdateTemplate saves changed content, declarations, parameters and tags as
+// a new version. A status is applied first, against the current version, through
+// the review workflow.
 func (u *promptUsecase) UpdateTemplate(ctx context.Context, payload *entities.UpdateTemplatePayload) (*entities.PromptTemplate, errors.BaseError) {
-	return u.repo.UpdateTemplate(ctx, payload)
+	if payload.Status != "" {
+		if err := u.setTemplateStatus(ctx, payload.ID, payload.Status, payload.Actor); err != nil {
+			return nil, err
+		}
+	}
+	contentChanged := payload.Content != "" || payload.Syntax != "" || payload.Kind != "" || len(payload.Messages) > 0
+	before, err := u.ownedTemplate(ctx, payload.ID)
+	if err != nil {
+		return nil, err
+	}
+	var current *entities.PromptTemplate
+	if contentChanged || payload.Variables != nil {
+		current = before
+	}
+	if contentChanged {
+		kind := payload.Kind
+		if kind == "" && len(payload.Messages) == 0 {
+			kind = currentKind(current.Kind)
+		}
+		content, err := chatContent(&kind, payload.Content, payload.Messages)
+		if err != nil {
+			return nil, err
+		}
+		payload.Content, payload.Kind = content, kind
+		if content == "" {
+			if kind != currentKind(current.Kind) {
+				return nil, errors.BadRequest("changing the kind of a template requires new content")
+			}
+			content = current.Content
+		}
+		syntax := payload.Syntax
+		if syntax == "" {
+			syntax = current.Syntax
+		}
+		if err := u.validateSyntax(content, kind, &syntax); err != nil {
+			return nil, err
+		}
+		includes, err := u.includesOf(ctx, current.Name, current.Locale, content, kind, syntax)
+		if err != nil {
+			return nil, err
+		}
+		payload.Includes = includes
+	}
+	if errs := helper.ValidateVariableDeclarations(payload.Variables); len(errs) > 0 {
+		return nil, errors.BadRequest("invalid variables: " + strings.Join(errs, "; "))
+	}
+	if errs := payload.Parameters.Validate(); len(errs) > 0 {
+		return nil, errors.BadRequest("invalid parameters: " + strings.Join(errs, "; "))
+	}
+	if payload.Tags != nil {
+		tags, err := normalizeTags(payload.Tags)
+		if err != nil {
+			return nil, err
+		}
+		payload.Tags = tags
+	}
+
+	// Content and declarations are checked together, whichever of them changed
+	var warnings []entities.LintIssue
+	if current != nil {
+		content, kind, syntax := payload.Content, payload.Kind, payload.Syntax
+		if content == "" {
+			content, kind = current.Content, currentKind(current.Kind)
+		}
+		if syntax == "" {
+			syntax = current.Syntax
+		}
+		vars, lintWarnings, err := u.declareVariables(ctx, current.Name, current.Locale, content, kind, syntax, payload.Variables, current.Variables)
+		if err != nil {
+			return nil, err
+		}
+		payload.Variables, warnings = vars, lintWarnings
+	}
+
+	var template *entities.PromptTemplate
+	err = u.audit.audited(ctx, func(ctx context.Context) errors.BaseError {
+		var err errors.BaseError
+		if template, err = u.repo.UpdateTemplate(ctx, payload); err != nil {
+			return err
+		}
+		// A status alone was audited as a status change
+		if current == nil && payload.Parameters == nil && payload.Tags == nil && payload.Description == nil {
+			return nil
+		}
+		return u.audit.record(ctx, entities.AuditUpdate, entities.AuditResourceTemplate, template.ID, before, template)
+	})
+	if err != nil {
+		return nil, err
+	}
+	template.Lint = warnings
+	return template, nil
 }
 
 func (u *promptUsecase) DeleteTemplate(ctx context.Context, id string) errors.BaseError {
-	return u.repo.DeleteTemplate(ctx, id)
+	before, err := u.ownedTemplate(ctx, id)
+	if err != nil {
+		return err
+	}
+	return u.audit.audited(ctx, func(ctx context.Context) errors.BaseError {
+		if err := u.repo.DeleteTemplate(ctx, id); err != nil {
+			return err
+		}
+		return u.audit.record(ctx, entities.AuditDelete, entities.AuditResourceTemplate, id, before, nil)
+	})
+}
+
+func (u *promptUsecase) ListTemplateVersions(ctx context.Context, templateID string) ([]*entities.PromptTemplateVersion, errors.BaseError) {
+	if _, err := u.repo.GetTemplate(ctx, templateID); err != nil {
+		return nil, err
+	}
+	return u.repo.ListTemplateVersions(ctx, templateID)
 }
 
+func (u *promptUsecase) GetTemplateVersion(ctx context.Context, templateID string, version string) (*entities.PromptTemplateVersion, errors.BaseError) {
+	number, ok := entities.ParseVersion(version)
+	if !ok {
+		return nil, errors.BadRequest(fmt.Sprintf("invalid version: %s", version))
+	}
+	if _, err := u.repo.GetTemplate(ctx, templateID); err != nil {
+		return nil, err
+	}
+	return u.repo.GetTemplateVersion(ctx, templateID, number)
+}
+
+// RollbackTemplate makes an older revision current again. The history is
+// never rewritten: the restored content is recorded as a new version. A
+// restored version that was approved keeps its approval, and production moves
+// to it along with the new version. Other versions are restored into draft,
+// leaving production where it is until the new version is reviewed.
+func (u *promptUsecase) RollbackTemplate(ctx context.Context, templateID string, version string) (*entities.PromptTemplate, errors.BaseError) {
+	target, err := u.GetTemplateVersion(ctx, templateID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := u.ownedTemplate(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Version == target.Version {
+		return nil, errors.BadRequest(fmt.Sprintf("%s is already the current version", target.Version))
+	}
+	approved, err := u.isApproved(ctx, templateID, target.Version)
+	if err != nil {
+		return nil, err
+	}
+	promote := approved && current.Status != entities.TemplateStatusArchived
+
+	// The partials may have changed since, so the includes are checked again
+	includes, err := u.includesOf(ctx, current.Name, current.Locale, target.Content, currentKind(target.Kind), target.Syntax)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := target.Variables
+	if variables == nil {
+		variables = []entities.Variable{}
+	}
+	parameters := target.Parameters
+	if parameters == nil {
+		parameters = &entities.ModelParameters{}
+	}
+	var template *entities.PromptTemplate
+	err = u.audit.audited(ctx, func(ctx context.Context) errors.BaseError {
+		var err errors.BaseError
+		template, err = u.repo.UpdateTemplate(ctx, &entities.UpdateTemplatePayload{
+			ID:         templateID,
+			Content:    target.Content,
+			Syntax:     target.Syntax,
+			Kind:       currentKind(target.Kind),
+			Parameters: parameters,
+			Variables:  variables,
+			Includes:   includes,
+		})
+		if err != nil {
+			return err
+		}
+		if promote {
+			if template, err = u.promoteRestored(ctx, template, target.Version); err != nil {
+				return err
+			}
+		}
+		return u.audit.record(ctx, entities.AuditUpdate, entities.AuditResourceTemplate, templateID, current, template)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// promoteRestored carries the approval of version over to the version that
+// restored it, activating the template, and moves production to it
+func (u *promptUsecase) promoteRestored(ctx context.Context, template *entities.PromptTemplate, version string) (*entities.PromptTemplate, errors.BaseError) {
+	restored, err := u.repo.ReviewTemplate(ctx, &entities.TemplateReview{
+		TemplateID: template.ID,
+		Version:    template.Version,
+		Action:     entities.ReviewRestore,
+		To:         entities.TemplateStatusActive,
+		Actor:      entities.RequestFromContext(ctx).Actor.ID,
+		Comment:    fmt.Sprintf("restored from %s", version),
+	}, []entities.TemplateStatus{entities.TemplateStatusDraft, entities.TemplateStatusReview, entities.TemplateStatusActive})
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := u.repo.GetTemplateLabel(ctx, template.ID, entities.LabelProduction)
+	if err != nil && err.GetCode() != errors.NOT_FOUND {
+		return nil, err
+	}
+	number, _ := entities.ParseVersion(restored.Version)
+	label, err := u.repo.SetTemplateLabel(ctx, template.ID, entities.LabelProduction, number, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.audit.record(ctx, entities.AuditConfig, entities.AuditResourceLabel, template.ID, before, label); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// RenderTemplate renders the named template. The name may select what to
+// render after an "@": a label ("ba-analysis-system@staging"), a pinned
+// revision ("ba-analysis-system@v3") or "latest" for the current content.
+// Without one the production label is rendered, which only serves approved
+// versions. Unpinned partials follow the same label, and the default locale's
+// variants of the template and its partials are rendered.
 func (u *promptUsecase) RenderTemplate(ctx context.Context, name string, variables map[string]string) (*entities.RenderedPrompt, errors.BaseError) {
-	template, err := u.repo.GetTemplateByName(ctx, name)
+	return u.RenderTemplateWithOptions(ctx, name, variables, &entities.RenderOptions{})
+}
+
+// RenderTemplateWithOptions renders like RenderTemplate and then checks the
+// token budget declared in the template's parameters. A prompt over budget is
+// rejected, or returned with a warning when the budget action is "warn".
+// The template and each partial are rendered in the first locale of the
+// fallback chain of opts.Locale they have a variant in.
+func (u *promptUsecase) RenderTemplateWithOptions(ctx context.Context, name string, variables map[string]string, opts *entities.RenderOptions) (*entities.RenderedPrompt, errors.BaseError) {
+	if errs := opts.Parameters.Validate(); len(errs) > 0 {
+		return nil, errors.BadRequest("invalid parameters: " + strings.Join(errs, "; "))
+	}
+	name, selector, _ := strings.Cut(name, "@")
+	if selector == "" {
+		selector = entities.LabelProduction
+	}
+	if opts.Locale != "" {
+		if _, ok := helper.NormalizeLocale(opts.Locale); !ok {
+			return nil, errors.BadRequest(fmt.Sprintf("invalid locale: %s", opts.Locale))
+		}
+	}
+	chain := u.locales.Chain(opts.Locale)
+	template, err := u.repo.GetTemplateByName(ctx, name, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, label, err := u.selectRevision(ctx, template, selector)
+	if err != nil {
+		return nil, err
+	}
+	content, syntax, kind, declared, version := revision.Content, revision.Syntax, currentKind(revision.Kind), revision.Variables, revision.Version
+	if label == "" {
+		label = entities.LabelProduction // partials of a pinned revision render as live
+	}
+
+	// Declared variables are validated against their type and always present
+	// so optional ones can be tested with {{if}}; undeclared values are passed
+	// through as supplied.
+	values, verrs := helper.CoerceVariables(declared, variables)
+	if len(verrs) > 0 {
+		return nil, errors.BadRequest("invalid variables: " + strings.Join(verrs, "; "))
+	}
+
+	partials, err := u.resolvePartials(ctx, template.Name, chain, content, kind, syntax, label)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &entities.RenderedPrompt{
+		Variables: variables,
+		Version:   version,
+		Locale:    template.Locale,
+	}
+	if params := revision.Parameters.Merge(opts.Parameters); !params.IsEmpty() {
+		result.Parameters = params
+	}
+	if kind == entities.TemplateKindChat {
+		messages, renderErr := u.renderMessages(content, syntax, values, partials)
+		if renderErr != nil {
+			return nil, errors.BadRequest(renderErr.Error())
+		}
+		encoded, _ := json.Marshal(messages)
+		result.Messages, result.Content = messages, string(encoded)
+	} else {
+		rendered, renderErr := u.engine.RenderWithPartials(content, syntax, values, partials)
+		if renderErr != nil {
+			return nil, errors.BadRequest(renderErr.Error())
+		}
+		result.Content = rendered
+	}
+	if _, pinned := entities.ParseVersion(selector); !pinned {
+		result.Label = selector
+	}
+	if err := u.checkBudget(result, opts.CountTokens); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// selectRevision resolves what follows the "@" of a template reference. It
+// returns the label the revision was found through, or "" for a pinned
+// version. Templates without a production label, which predate labels, serve
+// their current version as production. Production only serves versions a
+// reviewer approved, and never archived templates.
+func (u *promptUsecase) selectRevision(ctx context.Context, template *entities.PromptTemplate, selector string) (*entities.PromptTemplateVersion, string, errors.BaseError) {
+	if selector == entities.LabelProduction {
+		return u.productionRevision(ctx, template)
+	}
+	return u.labelledRevision(ctx, template, selector)
+}
+
+func (u *promptUsecase) productionRevision(ctx context.Context, template *entities.PromptTemplate) (*entities.PromptTemplateVersion, string, errors.BaseError) {
+	if template.Status == entities.TemplateStatusArchived {
+		return nil, "", errors.Conflict(fmt.Sprintf("template %s is archived", template.Name))
+	}
+	revision, label, err := u.labelledRevision(ctx, template, entities.LabelProduction)
+	if err != nil {
+		return nil, "", err
+	}
+	approved, err := u.isApproved(ctx, template.ID, revision.Version)
+	if err != nil {
+		return nil, "", err
+	}
+	if !approved {
+		return nil, "", errors.Conflict(fmt.Sprintf("%s %s has not been approved for production", template.Name, revision.Version))
+	}
+	return revision, label, nil
+}
+
+func (u *promptUsecase) labelledRevision(ctx context.Context, template *entities.PromptTemplate, selector string) (*entities.PromptTemplateVersion, string, errors.BaseError) {
+	if _, pinned := entities.ParseVersion(selector); pinned {
+		revision, err := u.GetTemplateVersion(ctx, template.ID, selector)
+		return revision, "", err
+	}
+	if selector != entities.LabelLatest && !entities.ValidLabel(selector) {
+		return nil, "", errors.BadRequest(fmt.Sprintf("invalid version or label: %s", selector))
+	}
+
+	if selector != entities.LabelLatest {
+		label, err := u.repo.GetTemplateLabel(ctx, template.ID, selector)
+		if err == nil {
+			number, _ := entities.ParseVersion(label.Version)
+			revision, err := u.repo.GetTemplateVersion(ctx, template.ID, number)
+			return revision, selector, err
+		}
+		if err.GetCode() != errors.NOT_FOUND || selector != entities.LabelProduction {
+			return nil, "", err
+		}
+	}
+
+	return &entities.PromptTemplateVersion{
+		TemplateID: template.ID,
+		Version:    template.Version,
+		Content:    template.Content,
+		Syntax:     template.Syntax,
+		Kind:       template.Kind,
+		Messages:   template.Messages,
+		Parameters: template.Parameters,
+		Variables:  template.Variables,
+		Tags:       template.Tags,
+	}, selector, nil
+}
+
+func (u *promptUsecase) ListTemplateLabels(ctx context.Context, templateID string) ([]*entities.TemplateLabel, errors.BaseError) {
+	if _, err := u.repo.GetTemplate(ctx, templateID); err != nil {
+		return nil, err
+	}
+	return u.repo.ListTemplateLabels(ctx, templateID)
+}
+
+// SetTemplateLabel moves a label to a version. If from is given the move
+// only happens while the label still points at that version. Production can
+// only be moved to a version a reviewer approved.
+func (u *promptUsecase) SetTemplateLabel(ctx context.Context, templateID string, label string, version string, from string) (*entities.TemplateLabel, errors.BaseError) {
+	if !entities.ValidLabel(label) {
+		return nil, errors.BadRequest(fmt.Sprintf("invalid label: %s", label))
+	}
+	number, ok := entities.ParseVersion(version)
+	if !ok {
+		return nil, errors.BadRequest(fmt.Sprintf("invalid version: %s", version))
+	}
+	expected := 0
+	if from != "" {
+		if expected, ok = entities.ParseVersion(from); !ok {
+			return nil, errors.BadRequest(fmt.Sprintf("invalid version: %s", from))
+		}
+	}
+	if _, err := u.ownedTemplate(ctx, templateID); err != nil {
+		return nil, err
+	}
+	if label == entities.LabelProduction {
+		approved, err := u.isApproved(ctx, templateID, entities.FormatVersion(number))
+		if err != nil {
+			return nil, err
+		}
+		if !approved {
+			return nil, errors.Conflict(fmt.Sprintf("%s has not been approved; submit it for review", entities.FormatVersion(number)))
+		}
+	}
+	before, err := u.repo.GetTemplateLabel(ctx, templateID, label)
+	if err != nil && err.GetCode() != errors.NOT_FOUND {
+		return nil, err
+	}
+	var result *entities.TemplateLabel
+	err = u.audit.audited(ctx, func(ctx context.Context) errors.BaseError {
+		var err errors.BaseError
+		if result, err = u.repo.SetTemplateLabel(ctx, templateID, label, number, expected); err != nil {
+			return err
+		}
+		return u.audit.record(ctx, entities.AuditConfig, entities.AuditResourceLabel, templateID, before, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteTemplateLabel removes a label. Production cannot be removed, since
+// renders would otherwise fall back to the latest, unreviewed content.
+func (u *promptUsecase) DeleteTemplateLabel(ctx context.Context, templateID string, label string) errors.BaseError {
+	if label == entities.LabelProduction {
+		return errors.BadRequest("the production label cannot be removed")
+	}
+	if _, err := u.ownedTemplate(ctx, templateID); err != nil {
+		return err
+	}
+	before, err := u.repo.GetTemplateLabel(ctx, templateID, label)
+	if err != nil {
+		return err
+	}
+	return u.audit.audited(ctx, func(ctx context.Context) errors.BaseError {
+		if err := u.repo.DeleteTemplateLabel(ctx, templateID, label); err != nil {
+			return err
+		}
+		return u.audit.record(ctx, entities.AuditConfig, entities.AuditResourceLabel, templateID, before, nil)
+	})
+}
+
+// ListTemplateDependents returns every template that includes the given
+// one, directly or through other partials, nearest first.
+func (u *promptUsecase) ListTemplateDependents(ctx context.Context, templateID string) ([]*entities.TemplateDependent, errors.BaseError) {
+	template, err := u.repo.GetTemplate(ctx, templateID)
 	if err != nil {
 		return nil, err
 	}
 
-	content := template.Content
+	// Includes are by name, so every locale variant of a dependent is listed
+	// at the depth its name is first reached, and each name is followed once
+	var dependents []*entities.TemplateDependent
+	depthOf := map[string]int{template.Name: 0}
+	listed := make(map[string]bool)
+	queue := []string{template.Name}
+	for depth := 1; len(queue) > 0; depth++ {
+		var next []string
+		for _, name := range queue {
+			direct, err := u.repo.ListIncludingTemplates(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range direct {
+				if first, ok := depthOf[d.Name]; (ok && first < depth) || listed[d.ID] {
+					continue
+				}
+				listed[d.ID] = true
+				d.Depth = depth
+				dependents = append(dependents, d)
+				if _, ok := depthOf[d.Name]; !ok {
+					depthOf[d.Name] = depth
+					next = append(next, d.Name)
+				}
+			}
+		}
+		queue = next
+	}
+	return dependents, nil
+}
+
+// includesOf checks that every partial reachable from content resolves in
+// locale and returns the partials content includes directly.
+func (u *promptUsecase) includesOf(ctx context.Context, name string, locale string, content string, kind entities.TemplateKind, syntax entities.TemplateSyntax) ([]entities.TemplateInclude, errors.BaseError) {
+	refs, err := u.extractIncludes(content, kind, syntax)
+	if err != nil {
+		return nil, errors.BadRequest(err.Error())
+	}
+	if _, err := u.resolvePartials(ctx, name, u.locales.Chain(locale), content, kind, syntax, entities.LabelLatest); err != nil {
+		return nil, err
+	}
 
-	// Validate variables and replace
-	for _, v := range template.Variables {
-		val, ok := variables[v.Name]
+	includes := make([]entities.TemplateInclude, 0, len(refs))
+	for _, ref := range refs {
+		include, _ := entities.ParseInclude(ref)
+		includes = append(includes, include)
+	}
+	return includes, nil
+}
+
+// resolvePartials loads every partial reachable from content, keyed by the
+// reference used to include it. A reference is a template name, optionally
+// pinned to a revision ("lang-vi@v2"); without a pin the partial's version
+// under label is used, falling back to its production label. Each partial is
+// taken from the first of locales it has a variant in. Cycles and nesting
+// beyond helper.MaxIncludeDepth are rejected.
+func (u *promptUsecase) resolvePartials(ctx context.Context, name string, locales []string, content string, kind entities.TemplateKind, syntax entities.TemplateSyntax, label string) (map[string]*helper.Partial, errors.BaseError) {
+	scope := partialScope{label: label, locales: locales}
+	partials := make(map[string]*helper.Partial)
+	if err := u.collectPartials(ctx, content, kind, syntax, []string{name}, scope, partials); err != nil {
+		return nil, err
+	}
+	return partials, nil
+}
+
+// partialScope is what unpinned partials are resolved against
+type partialScope struct {
+	label   string
+	locales []string
+}
+
+func (u *promptUsecase) collectPartials(ctx context.Context, content string, kind entities.TemplateKind, syntax entities.TemplateSyntax, path []string, scope partialScope, partials map[string]*helper.Partial) errors.BaseError {
+	refs, parseErr := u.extractIncludes(content, kind, syntax)
+	if parseErr != nil {
+		return errors.BadRequest(fmt.Sprintf("%s: %s", path[len(path)-1], parseErr.Error()))
+	}
+	if len(refs) > 0 && len(path) > helper.MaxIncludeDepth {
+		return errors.BadRequest(fmt.Sprintf("partials are nested deeper than %d levels: %s",
+			helper.MaxIncludeDepth, strings.Join(path, " -> ")))
+	}
+
+	for _, ref := range refs {
+		include, ok := entities.ParseInclude(ref)
 		if !ok {
-			if v.Required && v.DefaultValue == "" {
-				return nil, errors.BadRequest(fmt.Sprintf("missing required variable: %s", v.Name))
+			return errors.BadRequest(fmt.Sprintf("invalid include reference: %s", ref))
+		}
+		for _, ancestor := range path {
+			if ancestor == include.Name {
+				return errors.BadRequest(fmt.Sprintf("include cycle: %s -> %s", strings.Join(path, " -> "), include.Name))
 			}
-			val = v.DefaultValue
 		}
 
-		// Simple replacement logic (can be upgraded to text/template or pongo2)
-		// Syntax: {{variable}}
-		placeholder := fmt.Sprintf("{{%s}}", v.Name)
-		content = strings.ReplaceAll(content, placeholder, val)
+		partial, ok := partials[ref]
+		if !ok {
+			var err errors.BaseError
+			if partial, err = u.loadPartial(ctx, include, scope); err != nil {
+				return err
+			}
+			partials[ref] = partial
+		}
+		if err := u.collectPartials(ctx, partial.Content, entities.TemplateKindText, partial.Syntax, append(path[:len(path):len(path)], include.Name), scope, partials); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	return &entities.RenderedPrompt{
-		Content:   content,
-		Variables: variables,
-	}, nil
+func (u *promptUsecase) loadPartial(ctx context.Context, include entities.TemplateInclude, scope partialScope) (*helper.Partial, errors.BaseError) {
+	label := scope.label
+	template, err := u.repo.GetTemplateByName(ctx, include.Name, scope.locales)
+	if err != nil {
+		if err.GetCode() == errors.NOT_FOUND {
+			return nil, errors.BadRequest(fmt.Sprintf("included template not found: %s", include.Name))
+		}
+		return nil, err
+	}
+
+	selector := include.Version
+	if selector == "" {
+		selector = label
+	}
+	revision, _, err := u.selectRevision(ctx, template, selector)
+	if err != nil && include.Version == "" && err.GetCode() == errors.NOT_FOUND && label != entities.LabelProduction {
+		revision, _, err = u.selectRevision(ctx, template, entities.LabelProduction)
+	}
+	if err != nil {
+		if err.GetCode() == errors.NOT_FOUND {
+			return nil, errors.BadRequest(fmt.Sprintf("included template version not found: %s", include.Ref()))
+		}
+		return nil, err
+	}
+	if currentKind(revision.Kind) == entities.TemplateKindChat {
+		return nil, errors.BadRequest(fmt.Sprintf("chat template %s cannot be included", include.Name))
+	}
+	return &helper.Partial{Content: revision.Content, Syntax: revision.Syntax}, nil
+}
+
+func normalizeFilter(filter *entities.TemplateFilter) errors.BaseError {
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return errors.BadRequest(fmt.Sprintf("invalid status: %s", filter.Status))
+	}
+	if filter.Locale != "" {
+		locale, ok := helper.NormalizeLocale(filter.Locale)
+		if !ok {
+			return errors.BadRequest(fmt.Sprintf("invalid locale: %s", filter.Locale))
+		}
+		filter.Locale = locale
+	}
+	switch filter.TagMode {
+	case "":
+		filter.TagMode = entities.TagMatchAll
+	case entities.TagMatchAll, entities.TagMatchAny:
+	default:
+		return errors.BadRequest(fmt.Sprintf("invalid tag mode: %s", filter.TagMode))
+	}
+	tags, err := normalizeTags(filter.Tags)
+	if err != nil {
+		return err
+	}
+	filter.Tags = tags
+	return nil
+}
+
+// normalizeTags trims and lowercases tags and drops empty and duplicate ones,
+// so "Analysis" and "analysis " are the same tag.
+func normalizeTags(tags []string) ([]string, errors.BaseError) {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, errors.BadRequest(fmt.Sprintf("tag %q is longer than %d characters", tag, maxTagLength))
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result, nil
+}
+
+// validateSyntax defaults and checks the syntax flag and makes sure Go-syntax
+// content, or every message of a chat template, compiles before it is stored.
+func (u *promptUsecase) validateSyntax(content string, kind entities.TemplateKind, syntax *entities.TemplateSyntax) errors.BaseError {
+	if !kind.IsValid() {
+		return errors.BadRequest(fmt.Sprintf("unsupported template kind: %s", kind))
+	}
+	if *syntax == "" {
+		*syntax = entities.TemplateSyntaxGo
+	}
+	if !syntax.IsValid() {
+		return errors.BadRequest(fmt.Sprintf("unsupported template syntax: %s", *syntax))
+	}
+	if content == "" || *syntax != entities.TemplateSyntaxGo {
+		return nil
+	}
+
+	segments, err := templateSegments(content, kind)
+	if err != nil {
+		return errors.BadRequest(err.Error())
+	}
+	for _, s := range segments {
+		if _, err := u.engine.Parse(s.content); err != nil {
+			return errors.BadRequest(s.label + err.Error())
+		}
+	}
+	return nil
+}
+
+// currentKind treats rows written before template kinds existed as text
+func currentKind(kind entities.TemplateKind) entities.TemplateKind {
+	if kind == "" {
+		return entities.TemplateKindText
+	}
+	return kind
 }
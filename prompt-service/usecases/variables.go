@@ -0,0 +1,67 @@
+package usecases
+
+import (
+	"context"
+	"sort"
+
+	"github.com/blcvn/backend/services/prompt-service/common/errors"
+	"github.com/blcvn/backend/services/prompt-service/entities"
+	"github.com/blcvn/backend/services/prompt-service/helper"
+)
+
+// declareVariables settles the variables saved with content. Explicit
+// declarations, or else those already stored with the template, are
+// authoritative, so references they miss are lint errors; without any, they
+// are only warnings. The variables the content uses beyond them are inferred
+// afresh, so inferred variables the content no longer uses are dropped.
+func (u *promptUsecase) declareVariables(ctx context.Context, name string, locale string, content string, kind entities.TemplateKind, syntax entities.TemplateSyntax, explicit []entities.Variable, stored []entities.Variable) ([]entities.Variable, []entities.LintIssue, errors.BaseError) {
+	inferred, err := u.inferVariables(ctx, name, locale, content, kind, syntax)
+	if err != nil {
+		return nil, nil, err
+	}
+	declared := explicit
+	if declared == nil {
+		declared = stored
+	}
+	declared = helper.DeclaredVariables(declared)
+
+	warnings, err := u.checkLint(ctx, name, locale, content, kind, syntax, declared)
+	if err != nil {
+		return nil, nil, err
+	}
+	return helper.MergeVariables(declared, inferred), warnings, nil
+}
+
+// inferVariables lists the variables content needs, including those its
+// partials read, from the template AST. Variables that are only ever tested
+// or defaulted are inferred as optional.
+func (u *promptUsecase) inferVariables(ctx context.Context, name string, locale string, content string, kind entities.TemplateKind, syntax entities.TemplateSyntax) ([]entities.Variable, errors.BaseError) {
+	segments, segErr := templateSegments(content, kind)
+	if segErr != nil {
+		return nil, errors.BadRequest(segErr.Error())
+	}
+
+	var refs []helper.VariableRef
+	for _, s := range segments {
+		found, parseErr := u.engine.ExtractVariableRefs(s.content, syntax)
+		if parseErr != nil {
+			return nil, errors.BadRequest(s.label + parseErr.Error())
+		}
+		refs = append(refs, found...)
+	}
+
+	partials, err := u.resolvePartials(ctx, name, u.locales.Chain(locale), content, kind, syntax, entities.LabelLatest)
+	if err != nil {
+		return nil, err
+	}
+	refNames := make([]string, 0, len(partials))
+	for ref := range partials {
+		refNames = append(refNames, ref)
+	}
+	sort.Strings(refNames)
+	for _, ref := range refNames {
+		found, _ := u.engine.ExtractVariableRefs(partials[ref].Content, partials[ref].Syntax)
+		refs = append(refs, found...)
+	}
+	return helper.InferVariables(refs), nil
+}